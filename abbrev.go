@@ -0,0 +1,112 @@
+package qradix
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrAmbiguous = errors.New("ambiguous abbreviation")
+)
+
+// maxCandidates is the max number of candidates listed in AmbiguousError
+const maxCandidates = 10
+
+// AmbiguousError is returned if an abbreviation matches more than one key,
+// and errors.Is(err, ErrAmbiguous) is true for it.
+type AmbiguousError struct {
+	Abbrev     string
+	Candidates []string // Candidates are at most 10 matched keys in lexicographic order
+	Total      int      // Total is the number of all matched keys
+}
+
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf(
+		"%s: %s matches %d keys (%s)",
+		ErrAmbiguous,
+		e.Abbrev,
+		e.Total,
+		strings.Join(e.Candidates, ", "),
+	)
+}
+
+func (e *AmbiguousError) Unwrap() error {
+	return ErrAmbiguous
+}
+
+// ShortestUniquePrefix returns the shortest prefix of the key which can be resolved into the key by ResolveAbbrev,
+// it is the key itself if the key is a prefix of other keys.
+// It returns ErrNotExist if the key is not stored.
+func (T *RTree) ShortestUniquePrefix(key string) (string, error) {
+	T.m.RLock()
+	defer T.m.RUnlock()
+
+	if len(key) == 0 {
+		return "", ErrEmptyKey
+	}
+
+	var shortest string
+	children := T.root
+	base := ""
+	pathSuffix := key
+	for children != nil {
+		rune1, size := utf8.DecodeRuneInString(pathSuffix)
+		matchedNode, ok := children.Idx[rune1]
+		if !ok || !strings.HasPrefix(pathSuffix, matchedNode.Prefix) {
+			return "", ErrNotExist
+		}
+
+		if shortest == "" && matchedNode.count == 1 {
+			// all keys starting with base+rune1 are under matchedNode
+			shortest = base + pathSuffix[:size]
+		}
+		base += matchedNode.Prefix
+		pathSuffix = pathSuffix[len(matchedNode.Prefix):]
+		if len(pathSuffix) == 0 {
+			if matchedNode.Leaf == nil {
+				return "", ErrNotExist
+			} else if shortest == "" {
+				return key, nil
+			}
+			return shortest, nil
+		}
+		children = matchedNode.Children
+	}
+	return "", ErrNotExist
+}
+
+// ResolveAbbrev returns the key which is abbreviated as abbrev.
+// The abbreviation is resolved into a key if it is a stored key or it is the prefix of only one key.
+// It returns ErrNotExist if no key starts with abbrev,
+// or it returns an *AmbiguousError if more than one key starts with it.
+func (T *RTree) ResolveAbbrev(abbrev string) (string, error) {
+	T.m.RLock()
+	defer T.m.RUnlock()
+
+	if len(abbrev) == 0 {
+		return "", ErrEmptyKey
+	}
+
+	nodes, base := T.findPrefix(abbrev)
+	if len(nodes) == 0 {
+		return "", ErrNotExist
+	}
+	n := nodes[0]
+	if n.count == 1 || (n.Leaf != nil && base+n.Prefix == abbrev) {
+		// the abbreviation itself is preferred
+		for n.Leaf == nil {
+			n, base = n.Children, base+n.Prefix
+		}
+		return base + n.Prefix, nil
+	}
+
+	candidates := []KV{}
+	pageNode(n, base, "", maxCandidates, &candidates)
+	err := &AmbiguousError{Abbrev: abbrev, Total: n.count}
+	for _, candidate := range candidates {
+		err.Candidates = append(err.Candidates, candidate.Key)
+	}
+	return "", err
+}
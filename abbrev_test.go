@@ -0,0 +1,88 @@
+package qradix
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestAbbrev(t *testing.T) {
+	t.Run("test ShortestUniquePrefix and ResolveAbbrev", testAbbrev)
+	t.Run("test abbreviations with random keys", testAbbrevWithRandomKeys)
+}
+
+func testAbbrev(t *testing.T) {
+	tree := NewRTree()
+	for _, key := range []string{"a1b2c3", "a1b2d4", "a1e5", "b6", "b6f7", "中文"} {
+		tree.Insert(key, key)
+	}
+
+	shortests := map[string]string{
+		"a1b2c3": "a1b2c",
+		"a1b2d4": "a1b2d",
+		"a1e5":   "a1e",
+		"b6":     "b6",
+		"b6f7":   "b6f",
+		"中文":     "中",
+	}
+	for key, expected := range shortests {
+		shortest, err := tree.ShortestUniquePrefix(key)
+		if err != nil || shortest != expected {
+			t.Errorf("ShortestUniquePrefix(%s): got %s %v expect %s", key, shortest, err, expected)
+		}
+		resolved, err := tree.ResolveAbbrev(shortest)
+		if err != nil || resolved != key {
+			t.Errorf("ResolveAbbrev(%s): got %s %v expect %s", shortest, resolved, err, key)
+		}
+	}
+	for _, key := range []string{"a1", "a1b2c", "x"} {
+		if _, err := tree.ShortestUniquePrefix(key); err != ErrNotExist {
+			t.Errorf("ShortestUniquePrefix(%s): key should not exist: %v", key, err)
+		}
+	}
+
+	_, err := tree.ResolveAbbrev("a1")
+	var ambiguousErr *AmbiguousError
+	if !errors.Is(err, ErrAmbiguous) || !errors.As(err, &ambiguousErr) {
+		t.Fatalf("ResolveAbbrev: a1 should be ambiguous: %v", err)
+	}
+	if ambiguousErr.Total != 3 || fmt.Sprint(ambiguousErr.Candidates) != "[a1b2c3 a1b2d4 a1e5]" {
+		t.Errorf("ResolveAbbrev: got candidates %+v", ambiguousErr)
+	}
+	if _, err = tree.ResolveAbbrev("c"); err != ErrNotExist {
+		t.Errorf("ResolveAbbrev: c should not exist: %v", err)
+	}
+}
+
+func testAbbrevWithRandomKeys(t *testing.T) {
+	seedRand()
+	for i := 0; i < *testRound; i++ {
+		var actions []string
+		tree := NewRTree()
+		dict := make(map[string]string)
+		randomStrings := GetTestStrings()
+
+		for j := 0; j < *actionCount; j++ {
+			doRandomAction(&actions, randomStrings[rand.Intn(len(randomStrings))], tree, dict)
+		}
+
+		for key := range dict {
+			shortest, err := tree.ShortestUniquePrefix(key)
+			if err != nil {
+				t.Fatalf("ShortestUniquePrefix(%s): %s, seed: %d", key, err, *seed)
+			}
+			resolved, err := tree.ResolveAbbrev(shortest)
+			if err != nil || resolved != key {
+				printActions(actions)
+				printRTree(tree)
+				t.Fatalf("ResolveAbbrev(%s): got %s %v expect %s, seed: %d", shortest, resolved, err, key, *seed)
+			}
+			if len(shortest) > 1 {
+				if resolved, err = tree.ResolveAbbrev(shortest[:len(shortest)-1]); err == nil && resolved == key {
+					t.Fatalf("ShortestUniquePrefix(%s): %s is not the shortest, seed: %d", key, shortest, *seed)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package qradix
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Aggregator summarizes values, it must be a monoid:
+// Combine is associative and Identity is its identity element.
+// Values are combined by the order of their keys.
+type Aggregator interface {
+	// Identity returns the summary of no value
+	Identity() interface{}
+	// FromValue returns the summary of a single value
+	FromValue(val interface{}) interface{}
+	// Combine returns the summary of two adjacent summaries
+	Combine(a, b interface{}) interface{}
+}
+
+// SetAggregator caches the summary of values on every node,
+// and summaries are updated along the changed path after each modification.
+// Then the summary of all values under a prefix can be got by Aggregate.
+// Aggregation is disabled if agg is nil.
+func (T *RTree) SetAggregator(agg Aggregator) {
+	T.m.Lock()
+	defer T.m.Unlock()
+
+	T.aggregator = agg
+	// summaries are outdated if aggregation was disabled
+	T.markAll()
+	T.refresh()
+}
+
+// Aggregate returns the summary of all values whose keys start with the prefix,
+// it returns nil if no aggregator is set.
+func (T *RTree) Aggregate(prefix string) interface{} {
+	T.m.RLock()
+	defer T.m.RUnlock()
+
+	if T.aggregator == nil {
+		return nil
+	}
+
+	children := T.root
+	pathSuffix := prefix
+	for len(pathSuffix) > 0 {
+		if children == nil {
+			return T.aggregator.Identity()
+		}
+		rune1, _ := utf8.DecodeRuneInString(pathSuffix)
+		matchedNode, ok := children.Idx[rune1]
+		if !ok {
+			return T.aggregator.Identity()
+		}
+
+		if strings.HasPrefix(matchedNode.Prefix, pathSuffix) {
+			// all keys under matchedNode start with the prefix
+			return matchedNode.agg
+		} else if !strings.HasPrefix(pathSuffix, matchedNode.Prefix) {
+			return T.aggregator.Identity()
+		}
+		pathSuffix = pathSuffix[len(matchedNode.Prefix):]
+		children = matchedNode.Children
+	}
+
+	// the prefix is empty
+	summary := T.aggregator.Identity()
+	for _, n := range sortedLevel(children) {
+		summary = T.aggregator.Combine(summary, n.agg)
+	}
+	return summary
+}
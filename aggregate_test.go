@@ -0,0 +1,94 @@
+package qradix
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+type sumAggregator struct{}
+
+func (agg sumAggregator) Identity() interface{}                 { return 0 }
+func (agg sumAggregator) FromValue(val interface{}) interface{} { return len(val.(string)) }
+func (agg sumAggregator) Combine(a, b interface{}) interface{}  { return a.(int) + b.(int) }
+
+// joinAggregator is not commutative, so it checks the order of combining
+type joinAggregator struct{}
+
+func (agg joinAggregator) Identity() interface{}                 { return "" }
+func (agg joinAggregator) FromValue(val interface{}) interface{} { return val.(string) + "," }
+func (agg joinAggregator) Combine(a, b interface{}) interface{}  { return a.(string) + b.(string) }
+
+func TestAggregate(t *testing.T) {
+	t.Run("test Aggregate", testAggregate)
+	t.Run("test Aggregate with random keys", testAggregateWithRandomKeys)
+}
+
+func testAggregate(t *testing.T) {
+	tree := NewRTree()
+	for _, key := range []string{"b", "abd", "a", "abc", "中文"} {
+		tree.Insert(key, key)
+	}
+	if tree.Aggregate("a") != nil {
+		t.Fatal("Aggregate: it should be nil without aggregator")
+	}
+
+	tree.SetAggregator(joinAggregator{})
+	cases := map[string]string{
+		"":   "a,abc,abd,b,中文,",
+		"a":  "a,abc,abd,",
+		"ab": "abc,abd,",
+		"中":  "中文,",
+		"ac": "",
+	}
+	for prefix, expected := range cases {
+		if got := tree.Aggregate(prefix); got != expected {
+			t.Errorf("Aggregate(%s): got %s expect %s", prefix, got, expected)
+		}
+	}
+
+	tree.Remove("abc")
+	tree.Insert("ab", "ab")
+	tree.RemovePrefix("b")
+	if got := tree.Aggregate(""); got != "a,ab,abd,中文," {
+		t.Errorf("Aggregate: got %s after updating", got)
+	}
+}
+
+func testAggregateWithRandomKeys(t *testing.T) {
+	seedRand()
+	for i := 0; i < *testRound; i++ {
+		var actions []string
+		tree := NewRTree()
+		tree.SetAggregator(sumAggregator{})
+		dict := make(map[string]string)
+		randomStrings := GetTestStrings()
+
+		for j := 0; j < *actionCount; j++ {
+			doRandomAction(&actions, randomStrings[rand.Intn(len(randomStrings))], tree, dict)
+		}
+
+		keys := []string{}
+		for key := range dict {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for j := 0; j < *actionCount; j++ {
+			prefix := randomStrings[rand.Intn(len(randomStrings))]
+			prefix = prefix[:rand.Intn(len(prefix))+1]
+			expected := 0
+			for _, key := range keys {
+				if strings.HasPrefix(key, prefix) {
+					expected += len(key)
+				}
+			}
+
+			if got := tree.Aggregate(prefix); got != expected {
+				printActions(actions)
+				printRTree(tree)
+				t.Fatalf("Aggregate(%s): got %d expect %d, seed: %d", prefix, got, expected, *seed)
+			}
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package qradix
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+	"unicode/utf8"
+)
+
+// KV is a key and its value
+type KV struct {
+	Key string
+	Val interface{}
+}
+
+type kvsByKey []KV
+
+func (kvs kvsByKey) Len() int           { return len(kvs) }
+func (kvs kvsByKey) Less(i, j int) bool { return kvs[i].Key < kvs[j].Key }
+func (kvs kvsByKey) Swap(i, j int)      { kvs[i], kvs[j] = kvs[j], kvs[i] }
+
+// Builder collects keys and constructs a radix tree bottom-up in a single pass.
+// It is much faster than inserting keys one by one
+// because no node is split and no lock is acquired during building.
+// Builder is not safe for concurrent use.
+type Builder struct {
+	entries []KV
+	sorted  bool
+}
+
+// NewBuilder returns a new Builder
+func NewBuilder() *Builder {
+	return &Builder{sorted: true}
+}
+
+// Add adds a key and its value to the builder.
+// Keys can be added in any order, but adding them in sorted order saves sorting in Build.
+// Keys must be valid UTF-8 because nodes are split at rune boundaries.
+func (b *Builder) Add(key string, val interface{}) error {
+	if len(key) == 0 {
+		return ErrEmptyKey
+	} else if !utf8.ValidString(key) {
+		return ErrInvalidKey
+	}
+	if len(b.entries) > 0 && key < b.entries[len(b.entries)-1].Key {
+		b.sorted = false
+	}
+	b.entries = append(b.entries, KV{Key: key, Val: val})
+	return nil
+}
+
+// Build returns a new tree containing all added keys and then resets the builder.
+// If a key is added more than once, the last added value is kept.
+func (b *Builder) Build() *RTree {
+	entries := b.entries
+	if !b.sorted {
+		// stable sorting keeps the order of duplicated keys
+		sort.Stable(kvsByKey(entries))
+	}
+	b.entries, b.sorted = nil, true
+
+	T := NewRTree()
+	T.root, T.size = buildTree(entries)
+	T.refresh()
+	return T
+}
+
+// BuildParallel builds a tree from unsorted entries with a number of workers.
+// Entries are partitioned by the first runes of keys, which is same as the index of the first level,
+// then each partition is built into a subtree concurrently and finally subtrees are linked into one tree.
+// If a key is received more than once, the last received value is kept.
+// If workers is less than 1, the number of CPUs is used.
+// The channel is always drained, and ErrEmptyKey or ErrInvalidKey is returned if there is any empty or invalid key.
+func BuildParallel(entries <-chan KV, workers int) (*RTree, error) {
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+
+	var err error
+	partitions := map[rune][]KV{}
+	for entry := range entries {
+		if len(entry.Key) == 0 {
+			err = ErrEmptyKey
+			continue
+		} else if !utf8.ValidString(entry.Key) {
+			err = ErrInvalidKey
+			continue
+		}
+		rune1, _ := utf8.DecodeRuneInString(entry.Key)
+		partitions[rune1] = append(partitions[rune1], entry)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	runes := make([]rune, 0, len(partitions))
+	for rune1 := range partitions {
+		runes = append(runes, rune1)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+
+	T := NewRTree()
+	// subtrees[i] is built from partitions[runes[i]]
+	subtrees := make([]*node, len(runes))
+	sizes := make([]int, len(runes))
+	jobs := make(chan int, len(runes))
+	for i := range runes {
+		jobs <- i
+	}
+	close(jobs)
+
+	wg := &sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				partition := partitions[runes[i]]
+				sort.Stable(kvsByKey(partition))
+				partition = dedupSorted(partition)
+				subtrees[i] = buildNode(partition, 0)
+				sizes[i] = len(partition)
+				T.refreshNode(subtrees[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(subtrees) == 0 {
+		return T, nil
+	}
+	T.root = subtrees[0]
+	T.root.Idx = map[rune]*node{}
+	for i, subtree := range subtrees {
+		if i+1 < len(subtrees) {
+			subtree.Next = subtrees[i+1]
+		}
+		T.root.Idx[runes[i]] = subtree
+		T.size += sizes[i]
+	}
+	return T, nil
+}
+
+// buildTree builds nodes from sorted entries
+// it returns the first node of the first level and the number of keys
+func buildTree(entries []KV) (*node, int) {
+	entries = dedupSorted(entries)
+	if len(entries) == 0 {
+		return nil, 0
+	}
+	return buildLevel(entries, 0), len(entries)
+}
+
+// dedupSorted removes duplicated keys from sorted entries in place,
+// and the last one of duplicated keys is kept
+func dedupSorted(entries []KV) []KV {
+	deduped := entries[:0]
+	for i, entry := range entries {
+		if i+1 < len(entries) && entries[i+1].Key == entry.Key {
+			continue
+		}
+		deduped = append(deduped, entry)
+	}
+	return deduped
+}
+
+// buildLevel builds a level of nodes and returns the first one,
+// entries are sorted, unique and they share the same key[:offset]
+// which is longer than offset
+func buildLevel(entries []KV, offset int) *node {
+	var first, last *node
+	idx := map[rune]*node{}
+	for i := 0; i < len(entries); {
+		rune1, _ := utf8.DecodeRuneInString(entries[i].Key[offset:])
+		j := i + 1
+		for j < len(entries) {
+			rune2, _ := utf8.DecodeRuneInString(entries[j].Key[offset:])
+			if rune2 != rune1 {
+				break
+			}
+			j++
+		}
+
+		n := buildNode(entries[i:j], offset)
+		idx[rune1] = n
+		if first == nil {
+			first = n
+		} else {
+			last.Next = n
+		}
+		last = n
+		i = j
+	}
+
+	first.Idx = idx
+	return first
+}
+
+// buildNode builds the node for the group of entries which share the same first rune after offset
+func buildNode(group []KV, offset int) *node {
+	// common prefix of sorted keys is the common prefix of the first and the last one
+	firstKey, lastKey := group[0].Key, group[len(group)-1].Key
+	end := offset + commonPrefixLen(firstKey[offset:], lastKey[offset:])
+
+	n := &node{Prefix: firstKey[offset:end], count: len(group)}
+	rest := group
+	if len(firstKey) == end {
+		n.Leaf = &leafNode{Val: group[0].Val}
+		rest = group[1:]
+	}
+	if len(rest) > 0 {
+		n.Children = buildLevel(rest, end)
+	}
+	return n
+}
+
+// commonPrefixLen returns the length of common prefix of s1 and s2 in byte,
+// the length always ends at a rune boundary
+func commonPrefixLen(s1, s2 string) int {
+	i := 0
+	for i < len(s1) && i < len(s2) && s1[i] == s2[i] {
+		i++
+	}
+	for i > 0 &&
+		((i < len(s1) && !utf8.RuneStart(s1[i])) ||
+			(i < len(s2) && !utf8.RuneStart(s2[i]))) {
+		i--
+	}
+	return i
+}
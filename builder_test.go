@@ -0,0 +1,162 @@
+package qradix
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	t.Run("test Build", testBuild)
+	t.Run("test Build with random keys", testBuildWithRandomKeys)
+	t.Run("test BuildParallel", testBuildParallel)
+}
+
+func testBuild(t *testing.T) {
+	type TestCase struct {
+		desc    string
+		inserts [][]string
+		expect  map[string]string
+	}
+
+	testCases := []*TestCase{
+		&TestCase{
+			desc:    "sorted keys",
+			inserts: [][]string{{"a", "1"}, {"ab", "2"}, {"abc", "3"}, {"abd", "4"}, {"b", "5"}},
+			expect:  map[string]string{"a": "1", "ab": "2", "abc": "3", "abd": "4", "b": "5"},
+		},
+		&TestCase{
+			desc:    "unsorted keys with duplicates",
+			inserts: [][]string{{"b", "1"}, {"abd", "2"}, {"a", "3"}, {"b", "4"}, {"abc", "5"}},
+			expect:  map[string]string{"a": "3", "abc": "5", "abd": "2", "b": "4"},
+		},
+		&TestCase{
+			desc:    "multi-bytes runes sharing leading bytes",
+			inserts: [][]string{{"中文", "1"}, {"中国", "2"}, {"世界", "3"}},
+			expect:  map[string]string{"中文": "1", "中国": "2", "世界": "3"},
+		},
+		&TestCase{
+			desc:    "no key",
+			inserts: [][]string{},
+			expect:  map[string]string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		builder := NewBuilder()
+		for _, insert := range tc.inserts {
+			if err := builder.Add(insert[0], insert[1]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		rTree := builder.Build()
+
+		if rTree.Size() != len(tc.expect) {
+			t.Errorf("Build(%s): size got %d expect %d", tc.desc, rTree.Size(), len(tc.expect))
+		}
+		for key, expected := range tc.expect {
+			val, err := rTree.Get(key)
+			if err != nil {
+				t.Errorf("Build(%s): %s not found: %s", tc.desc, key, err)
+			} else if val.(string) != expected {
+				t.Errorf("Build(%s): %s got %s expect %s", tc.desc, key, val, expected)
+			}
+		}
+	}
+
+	if err := NewBuilder().Add("", ""); err != ErrEmptyKey {
+		t.Errorf("Build: empty key should not be added")
+	}
+	// common prefixes of invalid keys can't end at rune boundaries
+	builder := NewBuilder()
+	for _, key := range []string{"\xff\xfe", "\xff\xfd", "a\xff"} {
+		if err := builder.Add(key, ""); err != ErrInvalidKey {
+			t.Errorf("Build: invalid key %q should not be added", key)
+		}
+	}
+}
+
+func testBuildWithRandomKeys(t *testing.T) {
+	seedRand()
+	for i := 0; i < *testRound; i++ {
+		builder := NewBuilder()
+		dict := make(map[string]string)
+		randomStrings := GetTestStrings()
+
+		for j := 0; j < *actionCount; j++ {
+			key := randomStrings[rand.Intn(len(randomStrings))]
+			builder.Add(key, key)
+			dict[key] = key
+		}
+		tree := builder.Build()
+
+		if !isEqual(tree, dict) || tree.Size() != len(dict) {
+			printRTree(tree)
+			printMap(dict)
+			t.Fatalf("built tree is not identical to Map, seed: %d", *seed)
+		}
+
+		// the built tree should be still updatable
+		for j := 0; j < *actionCount; j++ {
+			key := randomStrings[rand.Intn(len(randomStrings))]
+			var actions []string
+			doRandomAction(&actions, key, tree, dict)
+		}
+		if !isEqual(tree, dict) || tree.Size() != len(dict) {
+			printRTree(tree)
+			printMap(dict)
+			t.Fatalf("updated tree is not identical to Map, seed: %d", *seed)
+		}
+	}
+}
+
+func testBuildParallel(t *testing.T) {
+	seedRand()
+	for i := 0; i < *testRound; i++ {
+		dict := make(map[string]string)
+		randomStrings := GetTestStrings()
+		entries := make(chan KV, 16)
+		go func() {
+			for j := 0; j < *actionCount; j++ {
+				key := randomStrings[rand.Intn(len(randomStrings))]
+				// values of the same key are different, and the last one should be kept
+				entries <- KV{Key: key, Val: fmt.Sprintf("%s-%d", key, j)}
+				dict[key] = fmt.Sprintf("%s-%d", key, j)
+			}
+			entries <- KV{Key: "中文", Val: "中文"}
+			dict["中文"] = "中文"
+			close(entries)
+		}()
+
+		tree, err := BuildParallel(entries, 1+rand.Intn(4))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tree.Size() != len(dict) {
+			t.Fatalf("BuildParallel: size got %d expect %d, seed: %d", tree.Size(), len(dict), *seed)
+		}
+		for key, expected := range dict {
+			val, err := tree.Get(key)
+			if err != nil || val.(string) != expected {
+				printRTree(tree)
+				t.Fatalf("BuildParallel: %s got %v expect %s, seed: %d", key, val, expected, *seed)
+			}
+		}
+	}
+
+	entries := make(chan KV, 2)
+	entries <- KV{Key: "a", Val: "a"}
+	entries <- KV{Key: "", Val: ""}
+	close(entries)
+	if _, err := BuildParallel(entries, 2); err != ErrEmptyKey {
+		t.Errorf("BuildParallel: empty key should not be accepted")
+	}
+
+	entries = make(chan KV, 2)
+	entries <- KV{Key: "\xff\xfe", Val: ""}
+	entries <- KV{Key: "\xff\xfd", Val: ""}
+	close(entries)
+	if _, err := BuildParallel(entries, 2); err != ErrInvalidKey {
+		t.Errorf("BuildParallel: invalid key should not be accepted")
+	}
+}
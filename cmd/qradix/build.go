@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	qradix "github.com/ihexxa/q-radix/v3"
+)
+
+// jsonRecord is a line of JSON lines input, values other than strings are stored as JSON texts
+type jsonRecord struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// runBuild reads key-value pairs and writes a tree file, the last value wins if a key is repeated
+func runBuild(args []string, stdin io.Reader, stdout io.Writer) (int, error) {
+	flags := flag.NewFlagSet("build", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	format := flags.String("format", "tsv", "format of the input: tsv, csv or jsonl")
+	output := flags.String("o", "", "path of the tree file, stdout by default")
+	if err := flags.Parse(args); err != nil || flags.NArg() > 1 {
+		return exitError, errUsage
+	}
+
+	input := stdin
+	if flags.NArg() == 1 {
+		file, err := os.Open(flags.Arg(0))
+		if err != nil {
+			return exitError, err
+		}
+		defer file.Close()
+		input = file
+	}
+
+	builder := qradix.NewBuilder()
+	add := func(line int, key, val string) error {
+		if !validKey(key) {
+			return fmt.Errorf("line %d: newlines and tabs are not supported in keys of tree files", line)
+		} else if !validRow(val) {
+			return fmt.Errorf("line %d: newlines are not supported in tree files", line)
+		} else if len(val) == 0 {
+			return fmt.Errorf("line %d: empty values are not supported in tree files", line)
+		} else if err := builder.Add(key, val); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		return nil
+	}
+
+	var err error
+	switch *format {
+	case "tsv":
+		err = readTSV(input, add)
+	case "csv":
+		err = readCSV(input, add)
+	case "jsonl":
+		err = readJSONLines(input, add)
+	default:
+		return exitError, fmt.Errorf("unknown format: %s", *format)
+	}
+	if err != nil {
+		return exitError, err
+	}
+
+	if *output != "" {
+		// the tree file is replaced only after it is written completely
+		if err = saveTree(*output, builder.Build()); err != nil {
+			return exitError, err
+		}
+		return exitOK, nil
+	}
+	return exitOK, writeTree(stdout, builder.Build())
+}
+
+// readTSV reads "key<TAB>value" lines, the value is the rest of the line after the first tab
+func readTSV(reader io.Reader, add func(line int, key, val string) error) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxRowSize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Text()) == 0 {
+			continue
+		}
+		parts := strings.SplitN(scanner.Text(), "\t", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("line %d: tab is not found", line)
+		}
+		if err := add(line, parts[0], parts[1]); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// readCSV reads records of two fields: key and value
+func readCSV(reader io.Reader, add func(line int, key, val string) error) error {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = 2
+	for line := 1; ; line++ {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err = add(line, record[0], record[1]); err != nil {
+			return err
+		}
+	}
+}
+
+// readJSONLines reads objects like {"key": "k", "value": "v"} line by line
+func readJSONLines(reader io.Reader, add func(line int, key, val string) error) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxRowSize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+
+		record := &jsonRecord{}
+		if err := json.Unmarshal(scanner.Bytes(), record); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		val := string(record.Value)
+		var str string
+		if err := json.Unmarshal(record.Value, &str); err == nil {
+			val = str
+		}
+		if err := add(line, record.Key, val); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,179 @@
+// Command qradix builds and queries tree files, which are radix trees serialized by RTree.String(),
+// one row per line.
+//
+// Usage:
+//
+//	qradix build [-format tsv|csv|jsonl] [-o file] [input]
+//	qradix get <file> <key>
+//	qradix prefix <file> <key>
+//	qradix longer [-limit n] <file> <key>
+//	qradix best <file> <key>
+//	qradix dump <file>
+//	qradix stats <file>
+//	qradix diff <old file> <new file>
+//	qradix repl <file>
+//
+// Keys and values are printed as "key<TAB>value" lines.
+// get and best exit with 1 if nothing is found, and diff exits with 1 if the trees are different.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	qradix "github.com/ihexxa/q-radix/v3"
+)
+
+const (
+	exitOK       = 0
+	exitNotFound = 1
+	exitError    = 2
+)
+
+// maxRowSize is the max size of a row in tree files
+const maxRowSize = 64 << 20
+
+var (
+	errUsage = errors.New("invalid arguments")
+)
+
+const usage = `usage: qradix <command> [arguments]
+
+commands:
+  build [-format tsv|csv|jsonl] [-o file] [input]  build a tree file from key-value pairs
+  get <file> <key>                                 print the value of the key
+  prefix <file> <key>                              print stored keys which are prefixes of the key
+  longer [-limit n] <file> <key>                   print stored keys which start with the key and are longer
+  best <file> <key>                                print the longest stored key which is a prefix of the key
+  dump <file>                                      print all keys and values in order
+  stats <file>                                     print statistics of the tree
+  diff <old file> <new file>                       print changes from the old tree to the new tree
+  repl <file>                                      explore and edit the tree interactively
+`
+
+// command runs a subcommand with its arguments and returns the exit code
+type command func(args []string, stdin io.Reader, stdout io.Writer) (int, error)
+
+var commands = map[string]command{
+	"build":  runBuild,
+	"get":    runGet,
+	"prefix": runPrefix,
+	"longer": runLonger,
+	"best":   runBest,
+	"dump":   runDump,
+	"stats":  runStats,
+	"diff":   runDiff,
+	"repl":   runRepl,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run dispatches args to the subcommand and returns the exit code
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitError
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command: %s\n%s", args[0], usage)
+		return exitError
+	}
+
+	writer := bufio.NewWriter(stdout)
+	code, err := cmd(args[1:], stdin, writer)
+	if flushErr := writer.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		if err == errUsage {
+			fmt.Fprintf(stderr, "qradix %s: %s\n%s", args[0], err, usage)
+		} else {
+			fmt.Fprintf(stderr, "qradix %s: %s\n", args[0], err)
+		}
+		return exitError
+	}
+	return code
+}
+
+// loadTree restores a tree from the tree file
+func loadTree(path string) (*qradix.RTree, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return readTree(file)
+}
+
+// readTree restores a tree from rows separated by newlines
+func readTree(reader io.Reader) (*qradix.RTree, error) {
+	rows := make(chan string, 512)
+	errChan := make(chan error, 1)
+	tree := qradix.NewRTree()
+	go func() {
+		err := tree.FromString(rows)
+		// drain rows if FromString fails
+		for range rows {
+		}
+		errChan <- err
+	}()
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxRowSize)
+	for scanner.Scan() {
+		if len(scanner.Text()) > 0 {
+			rows <- scanner.Text()
+		}
+	}
+	close(rows)
+
+	if err := <-errChan; err != nil {
+		return nil, err
+	}
+	return tree, scanner.Err()
+}
+
+// writeTree serializes the tree into rows separated by newlines
+func writeTree(writer io.Writer, tree *qradix.RTree) error {
+	rows := tree.String()
+	for row := range rows {
+		if _, err := io.WriteString(writer, row+"\n"); err != nil {
+			// drain rows to stop the serializing goroutine
+			for range rows {
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func printKV(writer io.Writer, key string, val interface{}) {
+	fmt.Fprintf(writer, "%s\t%v\n", key, val)
+}
+
+// treeAndKey parses "<file> <key>" arguments
+func treeAndKey(args []string) (*qradix.RTree, string, error) {
+	if len(args) != 2 {
+		return nil, "", errUsage
+	}
+	tree, err := loadTree(args[0])
+	return tree, args[1], err
+}
+
+// validRow returns false if s can not be stored in a row of tree files
+func validRow(s string) bool {
+	return !strings.ContainsAny(s, "\r\n")
+}
+
+// validKey returns false if s can not be stored as a key in tree files,
+// a segment ending with a tab is not read back because the key and the value in a row are separated by two tabs
+func validKey(s string) bool {
+	return validRow(s) && !strings.Contains(s, "\t")
+}
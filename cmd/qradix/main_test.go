@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommands(t *testing.T) {
+	t.Run("test build and queries", testBuildAndQueries)
+	t.Run("test build formats", testBuildFormats)
+	t.Run("test diff", testDiff)
+	t.Run("test invalid arguments", testInvalidArguments)
+}
+
+// runCmd runs the command and returns its exit code and output
+func runCmd(t *testing.T, stdin string, args ...string) (int, string) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run(args, strings.NewReader(stdin), stdout, stderr)
+	if code == exitError && stderr.Len() == 0 {
+		t.Errorf("%v: error message is not printed", args)
+	}
+	return code, stdout.String()
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "qradix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func testBuildAndQueries(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	treeFile := filepath.Join(dir, "t.tree")
+
+	input := "he\tv1\nhello\tv2\nhello世界\tv3\nhi\tv4\tv5\nhe\tV1\n"
+	if code, _ := runCmd(t, input, "build", "-o", treeFile); code != exitOK {
+		t.Fatalf("build: exit %d", code)
+	}
+
+	cases := []struct {
+		args   []string
+		code   int
+		output string
+	}{
+		{[]string{"get", treeFile, "hello"}, exitOK, "v2\n"},
+		{[]string{"get", treeFile, "hell"}, exitNotFound, ""},
+		{[]string{"get", treeFile, "he"}, exitOK, "V1\n"},
+		{[]string{"prefix", treeFile, "hello世界!"}, exitOK, "he\tV1\nhello\tv2\nhello世界\tv3\n"},
+		{[]string{"longer", treeFile, "he"}, exitOK, "hello\tv2\nhello世界\tv3\n"},
+		{[]string{"longer", "-limit", "1", treeFile, "h"}, exitOK, "he\tV1\n"},
+		{[]string{"best", treeFile, "hellx"}, exitOK, "he\tV1\n"},
+		{[]string{"best", treeFile, "x"}, exitNotFound, ""},
+		{[]string{"dump", treeFile}, exitOK, "he\tV1\nhello\tv2\nhello世界\tv3\nhi\tv4\tv5\n"},
+		{[]string{"stats", treeFile}, exitOK, "keys\t4\nnodes\t5\ndepth\t4\navg_key_bytes\t5.00\navg_segment_bytes\t2.40\n"},
+	}
+	for _, tc := range cases {
+		code, output := runCmd(t, "", tc.args...)
+		if code != tc.code || output != tc.output {
+			t.Errorf("%v: got %d %q expect %d %q", tc.args, code, output, tc.code, tc.output)
+		}
+	}
+}
+
+func testBuildFormats(t *testing.T) {
+	cases := []struct {
+		format string
+		input  string
+		dump   string
+	}{
+		{"csv", "a,\"x,y\"\nb,z\n", "a\tx,y\nb\tz\n"},
+		{"jsonl", "{\"key\":\"a\",\"value\":{\"x\":1}}\n\n{\"key\":\"b\",\"value\":\"s\"}\n", "a\t{\"x\":1}\nb\ts\n"},
+		// tabs in values are escaped in rows and read back
+		{"csv", "a,\"\tv\t+\"\nab,\"x\ty\"\n", "a\t\tv\t+\nab\tx\ty\n"},
+	}
+	for _, tc := range cases {
+		code, tree := runCmd(t, tc.input, "build", "-format", tc.format)
+		if code != exitOK {
+			t.Fatalf("build %s: exit %d", tc.format, code)
+		}
+		if dump := dumpRows(t, tree); dump != tc.dump {
+			t.Errorf("build %s: got %q expect %q", tc.format, dump, tc.dump)
+		}
+	}
+
+	for _, input := range []string{"a\n", "\tv\n", "a\t\n", "\xff\xfe\tv\n\xff\xfd\tv\n"} {
+		if code, _ := runCmd(t, input, "build"); code != exitError {
+			t.Errorf("build: %q should be rejected", input)
+		}
+	}
+	if code, _ := runCmd(t, "a,b,c\n", "build", "-format", "csv"); code != exitError {
+		t.Error("build: csv with 3 fields should be rejected")
+	}
+	if code, _ := runCmd(t, "{\"key\":\"a\",\"value\":\"x\\ny\"}\n", "build", "-format", "jsonl"); code != exitError {
+		t.Error("build: newlines should be rejected")
+	}
+	// a segment ending with a tab can't be read back from rows
+	if code, _ := runCmd(t, "\"a\tb\",v1\n\"a\t\",v2\n", "build", "-format", "csv"); code != exitError {
+		t.Error("build: tabs in keys should be rejected")
+	}
+	if code, _ := runCmd(t, "", "build", "-format", "xml"); code != exitError {
+		t.Error("build: unknown format should be rejected")
+	}
+}
+
+// dumpRows dumps the tree serialized in rows
+func dumpRows(t *testing.T, rows string) string {
+	tree, err := readTree(strings.NewReader(rows))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := &bytes.Buffer{}
+	after := ""
+	for {
+		items, next := tree.Page("", after, 1)
+		for _, kv := range items {
+			printKV(output, kv.Key, kv.Val)
+		}
+		if next == "" {
+			return output.String()
+		}
+		after = next
+	}
+}
+
+func testDiff(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	oldFile, newFile := filepath.Join(dir, "old.tree"), filepath.Join(dir, "new.tree")
+	runCmd(t, "a\t1\nab\t2\nb\t3\n", "build", "-o", oldFile)
+	runCmd(t, "a\t1\nab\t22\nc\t4\n", "build", "-o", newFile)
+
+	code, output := runCmd(t, "", "diff", oldFile, newFile)
+	if code != exitNotFound || output != "~\tab\t2\t22\n-\tb\t3\n+\tc\t4\n" {
+		t.Errorf("diff: got %d %q", code, output)
+	}
+	if code, output = runCmd(t, "", "diff", oldFile, oldFile); code != exitOK || output != "" {
+		t.Errorf("diff: got %d %q for identical trees", code, output)
+	}
+}
+
+func testInvalidArguments(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"unknown"},
+		{"get", "only-file"},
+		{"get", "not-exist.tree", "key"},
+		{"dump"},
+		{"longer", "-limit", "x", "file", "key"},
+		{"diff", "file"},
+		{"build", "-o", filepath.Join("not-exist", "t.tree")},
+	} {
+		if code, _ := runCmd(t, "", args...); code != exitError {
+			t.Errorf("%v: got exit %d", args, code)
+		}
+	}
+}
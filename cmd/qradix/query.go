@@ -0,0 +1,197 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+
+	qradix "github.com/ihexxa/q-radix/v3"
+)
+
+// pageSize is the number of keys fetched at a time by dump
+const pageSize = 1024
+
+func runGet(args []string, stdin io.Reader, stdout io.Writer) (int, error) {
+	tree, key, err := treeAndKey(args)
+	if err != nil {
+		return exitError, err
+	}
+	val, err := tree.Get(key)
+	if err == qradix.ErrNotExist {
+		return exitNotFound, nil
+	} else if err != nil {
+		return exitError, err
+	}
+	fmt.Fprintln(stdout, val)
+	return exitOK, nil
+}
+
+func runPrefix(args []string, stdin io.Reader, stdout io.Writer) (int, error) {
+	tree, key, err := treeAndKey(args)
+	if err != nil {
+		return exitError, err
+	}
+	for _, kv := range tree.PrefixMatches(key) {
+		printKV(stdout, kv.Key, kv.Val)
+	}
+	return exitOK, nil
+}
+
+func runLonger(args []string, stdin io.Reader, stdout io.Writer) (int, error) {
+	flags := flag.NewFlagSet("longer", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	limit := flags.Int("limit", 100, "max number of keys")
+	if err := flags.Parse(args); err != nil {
+		return exitError, errUsage
+	}
+	tree, key, err := treeAndKey(flags.Args())
+	if err != nil {
+		return exitError, err
+	}
+
+	// the key itself may be in the first page
+	items, _ := tree.Page(key, "", *limit+1)
+	printed := 0
+	for _, kv := range items {
+		if kv.Key != key && printed < *limit {
+			printKV(stdout, kv.Key, kv.Val)
+			printed++
+		}
+	}
+	return exitOK, nil
+}
+
+func runBest(args []string, stdin io.Reader, stdout io.Writer) (int, error) {
+	tree, key, err := treeAndKey(args)
+	if err != nil {
+		return exitError, err
+	}
+	bestKey, val, ok := tree.GetBestMatch(key)
+	if !ok {
+		return exitNotFound, nil
+	}
+	printKV(stdout, bestKey, val)
+	return exitOK, nil
+}
+
+func runDump(args []string, stdin io.Reader, stdout io.Writer) (int, error) {
+	if len(args) != 1 {
+		return exitError, errUsage
+	}
+	tree, err := loadTree(args[0])
+	if err != nil {
+		return exitError, err
+	}
+
+	after := ""
+	for {
+		items, next := tree.Page("", after, pageSize)
+		for _, kv := range items {
+			printKV(stdout, kv.Key, kv.Val)
+		}
+		if next == "" {
+			return exitOK, nil
+		}
+		after = next
+	}
+}
+
+// runStats prints the number of keys and nodes, the depth of the tree,
+// and the average length of keys and segments in bytes
+func runStats(args []string, stdin io.Reader, stdout io.Writer) (int, error) {
+	if len(args) != 1 {
+		return exitError, errUsage
+	}
+	tree, err := loadTree(args[0])
+	if err != nil {
+		return exitError, err
+	}
+
+	nodes, maxDepth, segmentBytes, keyBytes := 0, 0, 0, 0
+	var walk func(n qradix.Node, depth int, keyLen int)
+	walk = func(n qradix.Node, depth int, keyLen int) {
+		for ; n != nil; n = nextNode(n) {
+			nodes++
+			segmentBytes += len(n.Segment())
+			if depth > maxDepth {
+				maxDepth = depth
+			}
+			if _, ok := n.Value(); ok {
+				keyBytes += keyLen + len(n.Segment())
+			}
+			if child, ok := n.FirstChild(); ok {
+				walk(child, depth+1, keyLen+len(n.Segment()))
+			}
+		}
+	}
+	if root := rootNode(tree); root != nil {
+		walk(root, 1, 0)
+	}
+
+	stats := [][2]string{
+		{"keys", strconv.Itoa(tree.Size())},
+		{"nodes", strconv.Itoa(nodes)},
+		{"depth", strconv.Itoa(maxDepth)},
+		{"avg_key_bytes", average(keyBytes, tree.Size())},
+		{"avg_segment_bytes", average(segmentBytes, nodes)},
+	}
+	for _, stat := range stats {
+		fmt.Fprintf(stdout, "%s\t%s\n", stat[0], stat[1])
+	}
+	return exitOK, nil
+}
+
+// rootNode returns the first node of the first level, it returns nil if the tree is empty
+func rootNode(tree *qradix.RTree) qradix.Node {
+	if root, ok := tree.Root(); ok {
+		return root
+	}
+	return nil
+}
+
+func nextNode(n qradix.Node) qradix.Node {
+	if next, ok := n.NextNode(); ok {
+		return next
+	}
+	return nil
+}
+
+func average(total, count int) string {
+	if count == 0 {
+		return "0"
+	}
+	return strconv.FormatFloat(float64(total)/float64(count), 'f', 2, 64)
+}
+
+// runDiff prints "+<TAB>key<TAB>new", "-<TAB>key<TAB>old" or "~<TAB>key<TAB>old<TAB>new" for every changed key
+func runDiff(args []string, stdin io.Reader, stdout io.Writer) (int, error) {
+	if len(args) != 2 {
+		return exitError, errUsage
+	}
+	oldTree, err := loadTree(args[0])
+	if err != nil {
+		return exitError, err
+	}
+	newTree, err := loadTree(args[1])
+	if err != nil {
+		return exitError, err
+	}
+
+	changes := qradix.Diff(oldTree, newTree)
+	for _, change := range changes {
+		switch change.Type {
+		case qradix.KeyAdded:
+			fmt.Fprintf(stdout, "+\t%s\t%v\n", change.Key, change.New)
+		case qradix.KeyRemoved:
+			fmt.Fprintf(stdout, "-\t%s\t%v\n", change.Key, change.Old)
+		case qradix.KeyChanged:
+			fmt.Fprintf(stdout, "~\t%s\t%v\t%v\n", change.Key, change.Old, change.New)
+		}
+	}
+	if len(changes) > 0 {
+		return exitNotFound, nil
+	}
+	return exitOK, nil
+}
@@ -0,0 +1,492 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	qradix "github.com/ihexxa/q-radix/v3"
+)
+
+const (
+	replPrompt = "> "
+	// maxCandidates is the max number of keys listed by tab completion
+	maxCandidates = 20
+)
+
+const replHelp = `commands:
+  insert <key> <value>  insert or update a key, the value is the rest of the arguments
+  get <key>             print the value of the key
+  rm <key>              remove the key
+  prefix <key>          print stored keys which are prefixes of the key
+  best <key>            print the longest stored key which is a prefix of the key
+  walk [prefix]         print keys starting with the prefix in order
+  tree                  print nodes of the tree
+  save [file]           save the tree to the loaded file or another file
+  undo                  undo the last insert or rm
+  help                  print this message
+  quit                  exit
+keys containing spaces can be quoted like "a key", and tab completes commands and keys
+`
+
+// replCommands take keys as the first argument if it is true
+var replCommands = map[string]bool{
+	"insert": true,
+	"get":    true,
+	"rm":     true,
+	"prefix": true,
+	"best":   true,
+	"walk":   true,
+	"tree":   false,
+	"save":   false,
+	"undo":   false,
+	"help":   false,
+	"quit":   false,
+	"exit":   false,
+}
+
+// undoStep restores a key to its state before a modification
+type undoStep struct {
+	key     string
+	existed bool
+	val     interface{}
+}
+
+// repl is an interactive session on a tree
+type repl struct {
+	tree  *qradix.RTree
+	path  string
+	undos []*undoStep
+	out   io.Writer
+}
+
+// runRepl loads the tree file (or starts with an empty tree if it doesn't exist),
+// and executes commands read from stdin until quit or the end of input.
+func runRepl(args []string, stdin io.Reader, stdout io.Writer) (int, error) {
+	if len(args) != 1 {
+		return exitError, errUsage
+	}
+	tree, err := loadTree(args[0])
+	if os.IsNotExist(err) {
+		tree, err = qradix.NewRTree(), nil
+	}
+	if err != nil {
+		return exitError, err
+	}
+
+	r := &repl{tree: tree, path: args[0], out: stdout}
+	var readLine func() (string, error)
+	if file, ok := stdin.(*os.File); ok && isTerminal(file) {
+		restore, err := setRawMode(file)
+		if err == nil {
+			defer restore()
+			editor := &lineEditor{reader: bufio.NewReader(file), out: stdout, complete: r.complete}
+			readLine = editor.readLine
+		}
+	}
+	if readLine == nil {
+		reader := bufio.NewReader(stdin)
+		readLine = func() (string, error) {
+			fmt.Fprint(stdout, replPrompt)
+			flush(stdout)
+			line, err := reader.ReadString('\n')
+			if err == io.EOF && len(line) > 0 {
+				err = nil
+			}
+			return strings.TrimRight(line, "\r\n"), err
+		}
+	}
+
+	for {
+		line, err := readLine()
+		if err == io.EOF {
+			fmt.Fprintln(stdout)
+			return exitOK, nil
+		} else if err != nil {
+			return exitError, err
+		}
+		if !r.exec(line) {
+			return exitOK, nil
+		}
+		flush(stdout)
+	}
+}
+
+func flush(writer io.Writer) {
+	if flusher, ok := writer.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+}
+
+// exec executes a line of command, it returns false if the session should end
+func (r *repl) exec(line string) bool {
+	args, err := splitArgs(line)
+	if err != nil {
+		fmt.Fprintf(r.out, "error: %s\n", err)
+		return true
+	} else if len(args) == 0 {
+		return true
+	}
+
+	cmd, args := args[0], args[1:]
+	if _, ok := replCommands[cmd]; !ok {
+		fmt.Fprintf(r.out, "unknown command: %s, type help for commands\n", cmd)
+		return true
+	}
+	argCount := map[string][2]int{ // min and max number of arguments
+		"insert": {2, -1},
+		"get":    {1, 1},
+		"rm":     {1, 1},
+		"prefix": {1, 1},
+		"best":   {1, 1},
+		"walk":   {0, 1},
+		"save":   {0, 1},
+	}[cmd]
+	if len(args) < argCount[0] || (argCount[1] >= 0 && len(args) > argCount[1]) {
+		fmt.Fprintf(r.out, "invalid arguments, type help for usage\n")
+		return true
+	}
+
+	switch cmd {
+	case "insert":
+		r.insert(args[0], strings.Join(args[1:], " "))
+	case "get":
+		if val, err := r.tree.Get(args[0]); err != nil {
+			fmt.Fprintln(r.out, "(not found)")
+		} else {
+			fmt.Fprintln(r.out, val)
+		}
+	case "rm":
+		r.remove(args[0])
+	case "prefix":
+		for _, kv := range r.tree.PrefixMatches(args[0]) {
+			printKV(r.out, kv.Key, kv.Val)
+		}
+	case "best":
+		if key, val, ok := r.tree.GetBestMatch(args[0]); ok {
+			printKV(r.out, key, val)
+		} else {
+			fmt.Fprintln(r.out, "(not found)")
+		}
+	case "walk":
+		prefix := ""
+		if len(args) > 0 {
+			prefix = args[0]
+		}
+		r.walk(prefix)
+	case "tree":
+		printNodes(r.out, rootNode(r.tree), 0)
+	case "save":
+		path := r.path
+		if len(args) > 0 {
+			path = args[0]
+		}
+		if err := saveTree(path, r.tree); err != nil {
+			fmt.Fprintf(r.out, "error: %s\n", err)
+		} else {
+			fmt.Fprintf(r.out, "saved %d keys to %s\n", r.tree.Size(), path)
+		}
+	case "undo":
+		r.undo()
+	case "help":
+		fmt.Fprint(r.out, replHelp)
+	case "quit", "exit":
+		return false
+	}
+	return true
+}
+
+func (r *repl) insert(key, val string) {
+	if !validKey(key) {
+		fmt.Fprintln(r.out, "error: newlines and tabs are not supported in keys of tree files")
+		return
+	} else if !validRow(val) || len(val) == 0 {
+		fmt.Fprintln(r.out, "error: empty values and newlines are not supported in tree files")
+		return
+	}
+	oldVal, getErr := r.tree.Get(key)
+	if _, err := r.tree.Insert(key, val); err != nil {
+		fmt.Fprintf(r.out, "error: %s\n", err)
+		return
+	}
+	r.undos = append(r.undos, &undoStep{key: key, existed: getErr == nil, val: oldVal})
+	if getErr == nil {
+		fmt.Fprintf(r.out, "updated (old value: %v)\n", oldVal)
+	} else {
+		fmt.Fprintln(r.out, "inserted")
+	}
+}
+
+func (r *repl) remove(key string) {
+	oldVal, err := r.tree.Get(key)
+	if err != nil || !r.tree.Remove(key) {
+		fmt.Fprintln(r.out, "(not found)")
+		return
+	}
+	r.undos = append(r.undos, &undoStep{key: key, existed: true, val: oldVal})
+	fmt.Fprintln(r.out, "removed")
+}
+
+func (r *repl) undo() {
+	if len(r.undos) == 0 {
+		fmt.Fprintln(r.out, "nothing to undo")
+		return
+	}
+	step := r.undos[len(r.undos)-1]
+	r.undos = r.undos[:len(r.undos)-1]
+	if step.existed {
+		r.tree.Insert(step.key, step.val)
+		fmt.Fprintf(r.out, "restored %s\n", step.key)
+	} else {
+		r.tree.Remove(step.key)
+		fmt.Fprintf(r.out, "removed %s\n", step.key)
+	}
+}
+
+func (r *repl) walk(prefix string) {
+	after := ""
+	for {
+		items, next := r.tree.Page(prefix, after, pageSize)
+		for _, kv := range items {
+			printKV(r.out, kv.Key, kv.Val)
+		}
+		if next == "" {
+			return
+		}
+		after = next
+	}
+}
+
+// printNodes prints segments of nodes indented by their depth, and values after "="
+func printNodes(writer io.Writer, n qradix.Node, depth int) {
+	for ; n != nil; n = nextNode(n) {
+		fmt.Fprintf(writer, "%s%q", strings.Repeat("  ", depth), n.Segment())
+		if val, ok := n.Value(); ok {
+			fmt.Fprintf(writer, " = %v", val)
+		}
+		fmt.Fprintln(writer)
+		if child, ok := n.FirstChild(); ok {
+			printNodes(writer, child, depth+1)
+		}
+	}
+}
+
+// saveTree writes the tree to a temporary file and renames it to path,
+// so the file is not broken if writing fails
+func saveTree(path string, tree *qradix.RTree) error {
+	file, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	writer := bufio.NewWriter(file)
+	err = writeTree(writer, tree)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// splitArgs splits the line by spaces, and arguments in double quotes are unquoted like Go strings
+func splitArgs(line string) ([]string, error) {
+	args := []string{}
+	for {
+		line = strings.TrimLeft(line, " \t")
+		if len(line) == 0 {
+			return args, nil
+		}
+
+		if line[0] != '"' {
+			end := strings.IndexAny(line, " \t")
+			if end < 0 {
+				end = len(line)
+			}
+			args = append(args, line[:end])
+			line = line[end:]
+			continue
+		}
+
+		end := 1
+		for end < len(line) && line[end] != '"' {
+			if line[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(line) {
+			return nil, errors.New("unterminated quote")
+		}
+		arg, err := strconv.Unquote(line[:end+1])
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		line = line[end+1:]
+	}
+}
+
+// quoteArg quotes the argument if it can't be split as it is
+func quoteArg(arg string) string {
+	if len(arg) == 0 || strings.ContainsAny(arg, " \t\"") {
+		return strconv.Quote(arg)
+	}
+	return arg
+}
+
+// complete completes the last word of the line, which is a command or a key,
+// and returns the new line and candidates if the completion is ambiguous
+func (r *repl) complete(line string) (string, []string) {
+	start := strings.LastIndexAny(line, " \t") + 1
+	head, word := line[:start], line[start:]
+
+	if len(strings.TrimSpace(head)) == 0 {
+		candidates := []string{}
+		for cmd := range replCommands {
+			if strings.HasPrefix(cmd, word) {
+				candidates = append(candidates, cmd)
+			}
+		}
+		sort.Strings(candidates)
+		if len(candidates) == 1 {
+			return head + candidates[0] + " ", nil
+		}
+		return line, candidates
+	}
+
+	// only the first argument of commands taking keys is completed
+	args, err := splitArgs(head)
+	if err != nil || len(args) != 1 || !replCommands[args[0]] || strings.HasPrefix(word, "\"") {
+		return line, nil
+	}
+	completion, ok := r.tree.CompleteUnambiguous(word)
+	if !ok {
+		return line, nil
+	}
+	if r.tree.CountPrefix(completion) == 1 {
+		return head + quoteArg(completion) + " ", nil
+	} else if completion != word {
+		return head + quoteArg(completion), nil
+	}
+
+	candidates := []string{}
+	items, _ := r.tree.Page(word, "", maxCandidates)
+	for _, kv := range items {
+		candidates = append(candidates, kv.Key)
+	}
+	return line, candidates
+}
+
+// lineEditor reads lines from a terminal in raw mode, and completes the line when tab is pressed
+type lineEditor struct {
+	reader   *bufio.Reader
+	out      io.Writer
+	complete func(line string) (string, []string)
+}
+
+const (
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyBackspace = 8
+	keyTab       = '\t'
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// readLine reads a line, it returns io.EOF if Ctrl-D is pressed on an empty line
+func (e *lineEditor) readLine() (string, error) {
+	line := []rune{}
+	e.redraw(line)
+	for {
+		r, _, err := e.reader.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(e.out, "\r\n")
+			flush(e.out)
+			return string(line), nil
+		case keyCtrlC:
+			fmt.Fprint(e.out, "^C\r\n")
+			line = line[:0]
+		case keyCtrlD:
+			if len(line) == 0 {
+				return "", io.EOF
+			}
+		case keyBackspace, keyDelete:
+			if len(line) > 0 {
+				line = line[:len(line)-1]
+			}
+		case keyTab:
+			completed, candidates := e.complete(string(line))
+			line = []rune(completed)
+			if len(candidates) > 0 {
+				fmt.Fprintf(e.out, "\r\n%s\r\n", strings.Join(candidates, "  "))
+			}
+		case keyEscape:
+			// ignore escape sequences like arrow keys: ESC [ ... final byte
+			if next, _ := e.reader.Peek(1); len(next) > 0 && next[0] == '[' {
+				for {
+					b, err := e.reader.ReadByte()
+					if err != nil || (b >= '@' && b <= '~' && b != '[') {
+						break
+					}
+				}
+			}
+		default:
+			if r >= ' ' {
+				line = append(line, r)
+			}
+		}
+		e.redraw(line)
+	}
+}
+
+// redraw clears the current row of the terminal and prints the prompt with the line
+func (e *lineEditor) redraw(line []rune) {
+	fmt.Fprintf(e.out, "\r\033[K%s%s", replPrompt, string(line))
+	flush(e.out)
+}
+
+// isTerminal returns true if the file is a character device like a terminal
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// setRawMode disables line buffering, echo and signals of the terminal by stty,
+// and returns a function restoring the previous state
+func setRawMode(file *os.File) (func(), error) {
+	stty := func(args ...string) ([]byte, error) {
+		cmd := exec.Command("stty", args...)
+		cmd.Stdin = file
+		return cmd.Output()
+	}
+
+	state, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err = stty("-icanon", "-echo", "-isig", "min", "1"); err != nil {
+		return nil, err
+	}
+	return func() {
+		stty(strings.TrimSpace(string(state)))
+	}, nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	qradix "github.com/ihexxa/q-radix/v3"
+)
+
+func TestRepl(t *testing.T) {
+	t.Run("test repl commands", testReplCommands)
+	t.Run("test splitArgs", testSplitArgs)
+	t.Run("test completion", testCompletion)
+	t.Run("test lineEditor", testLineEditor)
+}
+
+func testReplCommands(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	treeFile := filepath.Join(dir, "r.tree")
+
+	script := strings.Join([]string{
+		`insert hello world`,
+		`insert he v1`,
+		`insert "a key" x  y`,
+		`get "a key"`,
+		`best hello!`,
+		`insert he v2`,
+		`undo`,
+		`get he`,
+		`rm hello`,
+		`rm hello`,
+		`undo`,
+		`prefix hello世界`,
+		`walk h`,
+		`tree`,
+		`save`,
+		`unknown`,
+		`get`,
+		`insert "unterminated`,
+		`insert "a\tkey" v`,
+		`undo`,
+		`undo`,
+		`undo`,
+		`undo`,
+		`quit`,
+		`get he`,
+	}, "\n")
+	code, output := runCmd(t, script, "repl", treeFile)
+	expected := strings.Join([]string{
+		"> inserted",
+		"> inserted",
+		"> inserted",
+		"> x y",
+		"> hello\tworld",
+		"> updated (old value: v1)",
+		"> restored he",
+		"> v1",
+		"> removed",
+		"> (not found)",
+		"> restored hello",
+		"> he\tv1\nhello\tworld",
+		"> he\tv1\nhello\tworld",
+		"> \"he\" = v1\n  \"llo\" = world\n\"a key\" = x y",
+		"> saved 3 keys to " + treeFile,
+		"> unknown command: unknown, type help for commands",
+		"> invalid arguments, type help for usage",
+		"> error: unterminated quote",
+		"> error: newlines and tabs are not supported in keys of tree files",
+		"> removed a key",
+		"> removed he",
+		"> removed hello",
+		"> nothing to undo",
+		"> ",
+	}, "\n")
+	if code != exitOK || output != expected {
+		t.Fatalf("repl: got %d\n%s\nexpect\n%s", code, output, expected)
+	}
+
+	// the saved tree is loaded
+	if code, output = runCmd(t, "walk", "repl", treeFile); output != "> a key\tx y\nhe\tv1\nhello\tworld\n> \n" {
+		t.Fatalf("repl: got %q after loading", output)
+	}
+}
+
+func testSplitArgs(t *testing.T) {
+	cases := map[string]string{
+		`get key`:            "get|key",
+		`  insert  k   v  `:  "insert|k|v",
+		`get "a key" "\t\""`: "get|a key|\t\"",
+		`get "世界"x`:          "get|世界|x",
+		`get ""`:             "get|",
+		"":                   "",
+	}
+	for line, expected := range cases {
+		args, err := splitArgs(line)
+		if err != nil || strings.Join(args, "|") != expected {
+			t.Errorf("splitArgs(%s): got %q %v expect %s", line, args, err, expected)
+		}
+	}
+	for _, line := range []string{`get "key`, `get "\x"`} {
+		if _, err := splitArgs(line); err == nil {
+			t.Errorf("splitArgs(%s): should fail", line)
+		}
+	}
+}
+
+func testCompletion(t *testing.T) {
+	tree := qradix.NewRTree()
+	for _, key := range []string{"hello", "help me", "world", "世界"} {
+		tree.Insert(key, key)
+	}
+	r := &repl{tree: tree, out: ioutil.Discard}
+
+	cases := []struct {
+		line       string
+		completed  string
+		candidates string
+	}{
+		{"ge", "get ", ""},
+		{"", "", "best,exit,get,help,insert,prefix,quit,rm,save,tree,undo,walk"},
+		{"get h", "get hel", ""},
+		{"get hel", "get hel", "hello,help me"},
+		{"get help", "get \"help me\" ", ""},
+		{"rm w", "rm world ", ""},
+		{"get 世", "get 世界 ", ""},
+		{"get x", "get x", ""},
+		{"save w", "save w", ""},
+		{"insert hello w", "insert hello w", ""},
+	}
+	for _, tc := range cases {
+		completed, candidates := r.complete(tc.line)
+		if completed != tc.completed || strings.Join(candidates, ",") != tc.candidates {
+			t.Errorf("complete(%s): got %q %v expect %q %s", tc.line, completed, candidates, tc.completed, tc.candidates)
+		}
+	}
+}
+
+func testLineEditor(t *testing.T) {
+	tree := qradix.NewRTree()
+	tree.Insert("hello", "world")
+	r := &repl{tree: tree, out: ioutil.Discard}
+
+	// tab, backspace, Ctrl-C, arrow keys and Ctrl-D
+	input := "ge\th\t\r" + "gex\x7f\x7ft\x1b[A\x1b[1;5C abc\x03" + "rm x\n" + "\x04"
+	out := &bytes.Buffer{}
+	editor := &lineEditor{reader: bufio.NewReader(strings.NewReader(input)), out: out, complete: r.complete}
+	for _, expected := range []string{"get hello ", "rm x"} {
+		if line, err := editor.readLine(); err != nil || line != expected {
+			t.Fatalf("readLine: got %q %v expect %q", line, err, expected)
+		}
+	}
+	if _, err := editor.readLine(); err != io.EOF {
+		t.Fatalf("readLine: got %v expect EOF", err)
+	}
+	if !strings.Contains(out.String(), "> get hello ") {
+		t.Fatalf("readLine: line is not echoed: %q", out.String())
+	}
+}
@@ -0,0 +1,57 @@
+package qradix
+
+// LongestCommonPrefix returns the longest prefix of the key along the edges of the tree,
+// even if no stored key ends there, and the node where the prefix ends.
+// So keys starting with the returned prefix exist in the tree,
+// and the node is nil if no key starts with the first rune of the key.
+func (T *RTree) LongestCommonPrefix(key string) (string, Node) {
+	T.m.RLock()
+	defer T.m.RUnlock()
+
+	c := newCursor(T)
+	matched := len(key)
+	for i, r := range key {
+		if !c.step(r) {
+			matched = i
+			break
+		}
+	}
+
+	if c.n == nil {
+		return "", nil
+	}
+	return key[:matched], c.n
+}
+
+// CompleteUnambiguous extends the prefix to the longest completion shared by all keys starting with the prefix,
+// like the tab completion of shells.
+// It returns false if no key starts with the prefix.
+func (T *RTree) CompleteUnambiguous(prefix string) (string, bool) {
+	T.m.RLock()
+	defer T.m.RUnlock()
+
+	c := newCursor(T)
+	if !c.stepString(prefix) {
+		return prefix, false
+	}
+
+	completion := prefix
+	n := c.n
+	if n == nil {
+		// the prefix is empty
+		if T.root == nil || T.root.Next != nil {
+			return completion, T.root != nil
+		}
+		n = T.root
+		completion = n.Prefix
+	} else {
+		completion += n.Prefix[c.off:]
+	}
+
+	// keys are ambiguous if there is a value or more than 1 child
+	for n.Leaf == nil && n.Children != nil && n.Children.Next == nil {
+		n = n.Children
+		completion += n.Prefix
+	}
+	return completion, true
+}
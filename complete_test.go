@@ -0,0 +1,76 @@
+package qradix
+
+import (
+	"testing"
+)
+
+func TestComplete(t *testing.T) {
+	t.Run("test LongestCommonPrefix", testLongestCommonPrefix)
+	t.Run("test CompleteUnambiguous", testCompleteUnambiguous)
+}
+
+func testLongestCommonPrefix(t *testing.T) {
+	tree := NewRTree()
+	for _, key := range []string{"abcd", "abce", "b", "中文"} {
+		tree.Insert(key, key)
+	}
+
+	cases := []struct {
+		key     string
+		matched string
+		segment string
+	}{
+		{"abcx", "abc", "abc"},
+		{"ab", "ab", "abc"},
+		{"abcdef", "abcd", "d"},
+		{"中国", "中", "中文"},
+		{"x", "", ""},
+	}
+	for _, tc := range cases {
+		matched, n := tree.LongestCommonPrefix(tc.key)
+		if matched != tc.matched {
+			t.Errorf("LongestCommonPrefix(%s): got %s expect %s", tc.key, matched, tc.matched)
+		}
+		if tc.segment == "" {
+			if n != nil {
+				t.Errorf("LongestCommonPrefix(%s): node should be nil", tc.key)
+			}
+		} else if n == nil || n.Segment() != tc.segment {
+			t.Errorf("LongestCommonPrefix(%s): node should be %s", tc.key, tc.segment)
+		}
+	}
+}
+
+func testCompleteUnambiguous(t *testing.T) {
+	tree := NewRTree()
+	if _, ok := tree.CompleteUnambiguous(""); ok {
+		t.Fatal("CompleteUnambiguous: empty tree has no completion")
+	}
+	tree.Insert("remote-add", "")
+	if completion, ok := tree.CompleteUnambiguous(""); !ok || completion != "remote-add" {
+		t.Fatalf("CompleteUnambiguous: got %s %t", completion, ok)
+	}
+
+	for _, key := range []string{"remote-remove", "rebase", "reset", "中文字典"} {
+		tree.Insert(key, "")
+	}
+	cases := []struct {
+		prefix     string
+		completion string
+		ok         bool
+	}{
+		{"remote-a", "remote-add", true},
+		{"rem", "remote-", true},
+		{"re", "re", true},
+		{"", "", true},
+		{"中", "中文字典", true},
+		{"x", "x", false},
+		{"remote-x", "remote-x", false},
+	}
+	for _, tc := range cases {
+		completion, ok := tree.CompleteUnambiguous(tc.prefix)
+		if completion != tc.completion || ok != tc.ok {
+			t.Errorf("CompleteUnambiguous(%s): got %s %t expect %s %t", tc.prefix, completion, ok, tc.completion, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package qradix
+
+import (
+	"unicode/utf8"
+)
+
+// cursor walks along the edges of a tree rune by rune,
+// it is a value type so it can be copied to walk along different branches
+type cursor struct {
+	root *node // root is the first node of the first level
+	n    *node // n is the node being matched, it is nil if nothing is matched
+	off  int   // n.Prefix[:off] is matched
+}
+
+func newCursor(T *RTree) cursor {
+	return cursor{root: T.root}
+}
+
+// step moves the cursor forward with the rune r,
+// it returns false and the cursor is not moved if there is no edge for r
+func (c *cursor) step(r rune) bool {
+	if c.n == nil || c.off == len(c.n.Prefix) {
+		children := c.root
+		if c.n != nil {
+			children = c.n.Children
+		}
+		if children == nil {
+			return false
+		}
+		next, ok := children.Idx[r]
+		if !ok {
+			return false
+		}
+		_, size := utf8.DecodeRuneInString(next.Prefix)
+		c.n, c.off = next, size
+		return true
+	}
+
+	nextRune, size := utf8.DecodeRuneInString(c.n.Prefix[c.off:])
+	if nextRune != r {
+		return false
+	}
+	c.off += size
+	return true
+}
+
+// stepString moves the cursor forward with all runes of s,
+// it returns false and the cursor may be moved partially if s is not along the edges
+func (c *cursor) stepString(s string) bool {
+	for _, r := range s {
+		if !c.step(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// value returns the value if the cursor stops at the end of a stored key
+func (c *cursor) value() (interface{}, bool) {
+	if c.n != nil && c.off == len(c.n.Prefix) && c.n.Leaf != nil {
+		return c.n.Leaf.Val, true
+	}
+	return nil, false
+}
+
+// more returns false if no longer key can be matched after the cursor
+func (c *cursor) more() bool {
+	return c.n == nil || c.off < len(c.n.Prefix) || c.n.Children != nil
+}
@@ -0,0 +1,176 @@
+package qradix
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+)
+
+// ChangeType is the type of a key's change
+type ChangeType int
+
+const (
+	// KeyAdded means the key only exists in the new tree
+	KeyAdded ChangeType = iota
+	// KeyRemoved means the key only exists in the old tree
+	KeyRemoved
+	// KeyChanged means the key exists in both trees with different values
+	KeyChanged
+)
+
+// String returns the name of the change type
+func (typ ChangeType) String() string {
+	switch typ {
+	case KeyAdded:
+		return "added"
+	case KeyRemoved:
+		return "removed"
+	case KeyChanged:
+		return "changed"
+	}
+	return fmt.Sprintf("ChangeType(%d)", int(typ))
+}
+
+// Change describes how a key is changed between two trees
+type Change struct {
+	Type ChangeType
+	Key  string
+	Old  interface{} // Old is nil if the key is added
+	New  interface{} // New is nil if the key is removed
+}
+
+// Diff returns changes which turn tree a into tree b, and changes are sorted by keys.
+// Both trees are walked in parallel, so only keys under different paths are visited.
+// If hashing is enabled in both trees with the same ValueHasher,
+// subtrees with the same hashes are also skipped.
+// Values are compared by reflect.DeepEqual.
+func Diff(a, b *RTree) []Change {
+	if a == b {
+		return []Change{}
+	}
+	unlock := lockPair(a, b, false)
+	defer unlock()
+
+	d := &differ{
+		hashed:  a.hashValue != nil && b.hashValue != nil,
+		changes: []Change{},
+	}
+	d.diffLevels(sortedLevel(a.root), sortedLevel(b.root), "")
+	return d.changes
+}
+
+type differ struct {
+	hashed  bool // if node hashes can be compared
+	changes []Change
+}
+
+// diffLevels compares two levels of nodes sorted by prefixes
+// base is the key of their parents
+func (d *differ) diffLevels(aLevel, bLevel []*node, base string) {
+	i, j := 0, 0
+	for i < len(aLevel) || j < len(bLevel) {
+		if j == len(bLevel) || (i < len(aLevel) && getRune1(aLevel[i].Prefix) < getRune1(bLevel[j].Prefix)) {
+			d.addChanges(aLevel[i], base, KeyRemoved)
+			i++
+		} else if i == len(aLevel) || getRune1(aLevel[i].Prefix) > getRune1(bLevel[j].Prefix) {
+			d.addChanges(bLevel[j], base, KeyAdded)
+			j++
+		} else {
+			d.diffNodes(aLevel[i], bLevel[j], base)
+			i++
+			j++
+		}
+	}
+}
+
+// diffNodes compares two nodes which share the same first rune
+func (d *differ) diffNodes(aNode, bNode *node, base string) {
+	if aNode == bNode {
+		return
+	} else if d.hashed &&
+		aNode.fresh && bNode.fresh &&
+		bytes.Equal(aNode.hash, bNode.hash) {
+		// identical subtrees
+		return
+	}
+
+	offset := commonPrefixLen(aNode.Prefix, bNode.Prefix)
+	if offset == len(aNode.Prefix) && offset == len(bNode.Prefix) {
+		key := base + aNode.Prefix
+		if aNode.Leaf != nil && bNode.Leaf != nil {
+			if !reflect.DeepEqual(aNode.Leaf.Val, bNode.Leaf.Val) {
+				d.changes = append(d.changes, Change{Type: KeyChanged, Key: key, Old: aNode.Leaf.Val, New: bNode.Leaf.Val})
+			}
+		} else if aNode.Leaf != nil {
+			d.changes = append(d.changes, Change{Type: KeyRemoved, Key: key, Old: aNode.Leaf.Val})
+		} else if bNode.Leaf != nil {
+			d.changes = append(d.changes, Change{Type: KeyAdded, Key: key, New: bNode.Leaf.Val})
+		}
+		d.diffLevels(sortedLevel(aNode.Children), sortedLevel(bNode.Children), key)
+	} else if offset == len(aNode.Prefix) {
+		// aNode is shorter, the rest of bNode is compared with aNode's children
+		key := base + aNode.Prefix
+		if aNode.Leaf != nil {
+			d.changes = append(d.changes, Change{Type: KeyRemoved, Key: key, Old: aNode.Leaf.Val})
+		}
+		d.diffLevels(sortedLevel(aNode.Children), []*node{restOf(bNode, offset)}, key)
+	} else if offset == len(bNode.Prefix) {
+		// bNode is shorter, the rest of aNode is compared with bNode's children
+		key := base + bNode.Prefix
+		if bNode.Leaf != nil {
+			d.changes = append(d.changes, Change{Type: KeyAdded, Key: key, New: bNode.Leaf.Val})
+		}
+		d.diffLevels([]*node{restOf(aNode, offset)}, sortedLevel(bNode.Children), key)
+	} else if aNode.Prefix < bNode.Prefix {
+		// no key is shared
+		d.addChanges(aNode, base, KeyRemoved)
+		d.addChanges(bNode, base, KeyAdded)
+	} else {
+		d.addChanges(bNode, base, KeyAdded)
+		d.addChanges(aNode, base, KeyRemoved)
+	}
+}
+
+// restOf returns a read-only view of n whose prefix is n.Prefix[offset:]
+func restOf(n *node, offset int) *node {
+	return &node{
+		Prefix:   n.Prefix[offset:],
+		Children: n.Children,
+		Leaf:     n.Leaf,
+	}
+}
+
+// addChanges adds all keys under n as the same type of changes
+func (d *differ) addChanges(n *node, base string, typ ChangeType) {
+	walkSorted(n, base, func(key string, val interface{}) {
+		if typ == KeyRemoved {
+			d.changes = append(d.changes, Change{Type: typ, Key: key, Old: val})
+		} else {
+			d.changes = append(d.changes, Change{Type: typ, Key: key, New: val})
+		}
+	})
+}
+
+// Apply applies changes to the tree in order, for example, changes returned by Diff.
+// Added or changed keys are set to the new values and removed keys are deleted.
+// It stops at the first invalid change and returns the error.
+func (T *RTree) Apply(changes []Change) error {
+	T.m.Lock()
+	defer T.m.Unlock()
+	// hashes are updated once after all changes are applied
+	defer T.refresh()
+
+	for _, change := range changes {
+		switch change.Type {
+		case KeyAdded, KeyChanged:
+			if _, err := T.insert(change.Key, change.New); err != nil {
+				return fmt.Errorf("applying change of %s error: %w", change.Key, err)
+			}
+		case KeyRemoved:
+			T.remove(change.Key)
+		default:
+			return fmt.Errorf("invalid change type %s", change.Type)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package qradix
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	t.Run("test Diff", testDiff)
+	t.Run("test Diff and Apply with random keys", testDiffAndApplyWithRandomKeys)
+	t.Run("test Diff concurrently", testDiffConcurrently)
+}
+
+func testDiff(t *testing.T) {
+	type TestCase struct {
+		desc   string
+		a      [][]string
+		b      [][]string
+		expect []Change
+	}
+
+	testCases := []*TestCase{
+		&TestCase{
+			desc: "identical trees",
+			a:    [][]string{{"a", "1"}, {"ab", "2"}},
+			b:    [][]string{{"ab", "2"}, {"a", "1"}},
+		},
+		&TestCase{
+			desc: "added, removed and changed",
+			a:    [][]string{{"a", "1"}, {"ab", "2"}, {"b", "3"}},
+			b:    [][]string{{"a", "1"}, {"ab", "4"}, {"c", "5"}},
+			expect: []Change{
+				Change{Type: KeyChanged, Key: "ab", Old: "2", New: "4"},
+				Change{Type: KeyRemoved, Key: "b", Old: "3"},
+				Change{Type: KeyAdded, Key: "c", New: "5"},
+			},
+		},
+		&TestCase{
+			desc: "prefixes are split differently",
+			a:    [][]string{{"abc", "1"}, {"abd", "2"}},
+			b:    [][]string{{"a", "0"}, {"abc", "1"}, {"abe", "3"}},
+			expect: []Change{
+				Change{Type: KeyAdded, Key: "a", New: "0"},
+				Change{Type: KeyRemoved, Key: "abd", Old: "2"},
+				Change{Type: KeyAdded, Key: "abe", New: "3"},
+			},
+		},
+		&TestCase{
+			desc: "diverged prefixes",
+			a:    [][]string{{"中文", "1"}},
+			b:    [][]string{{"中国", "2"}},
+			expect: []Change{
+				Change{Type: KeyAdded, Key: "中国", New: "2"},
+				Change{Type: KeyRemoved, Key: "中文", Old: "1"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		a, b := NewRTree(), NewRTree()
+		for _, insert := range tc.a {
+			a.Insert(insert[0], insert[1])
+		}
+		for _, insert := range tc.b {
+			b.Insert(insert[0], insert[1])
+		}
+
+		changes := Diff(a, b)
+		if len(changes) != len(tc.expect) {
+			t.Errorf("Diff(%s): got %+v expect %+v", tc.desc, changes, tc.expect)
+			continue
+		}
+		for i, change := range changes {
+			if change != tc.expect[i] {
+				t.Errorf("Diff(%s): got %+v expect %+v", tc.desc, change, tc.expect[i])
+			}
+		}
+	}
+}
+
+func testDiffAndApplyWithRandomKeys(t *testing.T) {
+	seedRand()
+	for i := 0; i < *testRound; i++ {
+		var actions []string
+		a, b := NewRTree(), NewRTree()
+		dict := make(map[string]string)
+		randomStrings := GetTestStrings()
+
+		for j := 0; j < *actionCount; j++ {
+			doRandomAction(&actions, randomStrings[rand.Intn(len(randomStrings))], a, nil)
+			doRandomAction(&actions, randomStrings[rand.Intn(len(randomStrings))], b, dict)
+		}
+
+		changes := Diff(a, b)
+		if !sort.SliceIsSorted(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key }) {
+			t.Fatalf("changes are not sorted %+v, seed: %d", changes, *seed)
+		}
+		if err := a.Apply(changes); err != nil {
+			t.Fatal(err)
+		}
+		if !isEqual(a, dict) || a.Size() != len(dict) {
+			printActions(actions)
+			printRTree(a)
+			printMap(dict)
+			t.Fatalf("patched tree is not identical to Map, seed: %d", *seed)
+		}
+		if changes = Diff(a, b); len(changes) != 0 {
+			t.Fatalf("patched tree should have no diff %+v, seed: %d", changes, *seed)
+		}
+	}
+}
+
+func testDiffConcurrently(t *testing.T) {
+	a, b := NewRTree(), NewRTree()
+	for _, key := range GetTestStrings() {
+		a.Insert("a"+key, key)
+		b.Insert("b"+key, key)
+	}
+
+	// writers are queued while trees are diffed in both directions
+	wg := &sync.WaitGroup{}
+	for _, pair := range [][2]*RTree{{a, b}, {b, a}} {
+		from, to := pair[0], pair[1]
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				Diff(from, to)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				from.Insert("c", "c")
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Diff is deadlocked")
+	}
+}
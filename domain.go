@@ -0,0 +1,209 @@
+package qradix
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrInvalidDomain = errors.New("invalid domain")
+)
+
+const (
+	labelSeparator = "."
+	wildcardLabel  = "*"
+	exceptionMark  = "!"
+)
+
+// DomainTable stores hostnames (e.g. "example.com") and wildcard domains (e.g. "*.example.com"),
+// and finds the most specific rule for a host.
+// Domains are stored as reversed labels ending with "." (e.g. "com.example."),
+// so rules of a host are prefixes of its reversed labels and are found in one walk from the root.
+// It also answers public suffix queries with rules in the syntax of the Public Suffix List.
+// Domains are case-insensitive and a trailing "." is ignored.
+// DomainTable is safe for concurrent use.
+type DomainTable struct {
+	hosts      *RTree // hosts stores hostnames matching only themselves
+	wildcards  *RTree // wildcards stores "*.example.com" as "com.example." matching only subdomains
+	suffixes   *RTree // suffixes stores public suffix rules like "com"
+	wildSuffix *RTree // wildSuffix stores public suffix rules like "*.ck" as "ck."
+	exceptions *RTree // exceptions stores public suffix rules like "!www.ck" as "ck.www."
+}
+
+// NewDomainTable returns an empty DomainTable
+func NewDomainTable() *DomainTable {
+	return &DomainTable{
+		hosts:      NewRTree(),
+		wildcards:  NewRTree(),
+		suffixes:   NewRTree(),
+		wildSuffix: NewRTree(),
+		exceptions: NewRTree(),
+	}
+}
+
+// normalizeDomain lowercases the domain and removes the trailing ".",
+// it returns false if there is an empty label or a wildcard label.
+func normalizeDomain(domain string) (string, bool) {
+	domain = strings.ToLower(strings.TrimSuffix(domain, labelSeparator))
+	if len(domain) == 0 {
+		return "", false
+	}
+	for _, label := range strings.Split(domain, labelSeparator) {
+		if len(label) == 0 || strings.Contains(label, wildcardLabel) {
+			return "", false
+		}
+	}
+	return domain, true
+}
+
+// reverseLabels converts "www.example.com" to "com.example.www."
+func reverseLabels(domain string) string {
+	labels := strings.Split(domain, labelSeparator)
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+	return strings.Join(labels, labelSeparator) + labelSeparator
+}
+
+// restoreLabels converts "com.example.www." to "www.example.com"
+func restoreLabels(key string) string {
+	return strings.TrimSuffix(reverseLabels(strings.TrimSuffix(key, labelSeparator)), labelSeparator)
+}
+
+// parseRule splits the rule into its tree and key,
+// wildcard must be the tree for "*." rules and exception must be the tree for "!" rules (or nil if unsupported)
+func parseRule(rule string, exact, wildcard, exception *RTree) (*RTree, string, error) {
+	tree := exact
+	if strings.HasPrefix(rule, wildcardLabel+labelSeparator) {
+		tree, rule = wildcard, rule[len(wildcardLabel+labelSeparator):]
+	} else if exception != nil && strings.HasPrefix(rule, exceptionMark) {
+		tree, rule = exception, rule[len(exceptionMark):]
+	}
+
+	domain, ok := normalizeDomain(rule)
+	if !ok {
+		return nil, "", ErrInvalidDomain
+	}
+	return tree, reverseLabels(domain), nil
+}
+
+// Insert stores the value with the domain, which is a hostname or a wildcard domain like "*.example.com",
+// and the old value is returned if the domain exists
+func (D *DomainTable) Insert(domain string, val interface{}) (interface{}, error) {
+	tree, key, err := parseRule(domain, D.hosts, D.wildcards, nil)
+	if err != nil {
+		return nil, err
+	}
+	return tree.Insert(key, val)
+}
+
+// Remove removes the hostname or the wildcard domain, it returns false if the domain doesn't exist
+func (D *DomainTable) Remove(domain string) bool {
+	tree, key, err := parseRule(domain, D.hosts, D.wildcards, nil)
+	if err != nil {
+		return false
+	}
+	return tree.Remove(key)
+}
+
+// Size returns the number of hostnames and wildcard domains
+func (D *DomainTable) Size() int {
+	return D.hosts.Size() + D.wildcards.Size()
+}
+
+// LookupHost returns the most specific domain matching the host and its value:
+// the hostname itself if it exists, or the longest wildcard domain which the host is a subdomain of.
+func (D *DomainTable) LookupHost(host string) (string, interface{}, bool) {
+	host, ok := normalizeDomain(host)
+	if !ok {
+		return "", nil, false
+	}
+
+	key := reverseLabels(host)
+	if val, err := D.hosts.Get(key); err == nil {
+		return host, val, true
+	}
+
+	var rule string
+	var ruleVal interface{}
+	D.wildcards.WalkPath(key, func(prefix string, val interface{}) bool {
+		if len(prefix) < len(key) {
+			rule, ruleVal = prefix, val
+			return true
+		}
+		return false
+	})
+	if len(rule) == 0 {
+		return "", nil, false
+	}
+	return wildcardLabel + labelSeparator + restoreLabels(rule), ruleVal, true
+}
+
+// AddPublicSuffix adds a rule in the syntax of the Public Suffix List,
+// e.g. "com", "*.ck" (any label under "ck") or "!www.ck" (an exception of wildcard rules).
+func (D *DomainTable) AddPublicSuffix(rule string) error {
+	tree, key, err := parseRule(rule, D.suffixes, D.wildSuffix, D.exceptions)
+	if err != nil {
+		return err
+	} else if tree == D.exceptions && strings.Count(key, labelSeparator) < 2 {
+		// an exception rule must leave at least one label as the public suffix
+		return ErrInvalidDomain
+	}
+	_, err = tree.Insert(key, true)
+	return err
+}
+
+// PublicSuffix returns the public suffix of the host by the algorithm of the Public Suffix List:
+// an exception rule has the highest priority, otherwise the rule with the most labels is used,
+// and the last label is the public suffix if no rule matches.
+// It returns an empty string if the host is invalid.
+func (D *DomainTable) PublicSuffix(host string) string {
+	host, ok := normalizeDomain(host)
+	if !ok {
+		return ""
+	}
+	key := reverseLabels(host)
+	labels := strings.Split(host, labelSeparator)
+
+	exceptionLabels := 0
+	D.exceptions.WalkPath(key, func(prefix string, val interface{}) bool {
+		exceptionLabels = strings.Count(prefix, labelSeparator)
+		return true
+	})
+
+	suffixLabels := 1
+	if exceptionLabels > 0 {
+		// the leftmost label of an exception rule is not a part of the public suffix
+		suffixLabels = exceptionLabels - 1
+	} else {
+		D.suffixes.WalkPath(key, func(prefix string, val interface{}) bool {
+			if count := strings.Count(prefix, labelSeparator); count > suffixLabels {
+				suffixLabels = count
+			}
+			return true
+		})
+		D.wildSuffix.WalkPath(key, func(prefix string, val interface{}) bool {
+			if count := strings.Count(prefix, labelSeparator) + 1; count > suffixLabels && count <= len(labels) {
+				suffixLabels = count
+			}
+			return true
+		})
+	}
+	return strings.Join(labels[len(labels)-suffixLabels:], labelSeparator)
+}
+
+// RegistrableDomain returns the public suffix of the host with one more label (e.g. "example.co.uk"),
+// it returns false if the host is invalid or is a public suffix itself.
+func (D *DomainTable) RegistrableDomain(host string) (string, bool) {
+	host, ok := normalizeDomain(host)
+	if !ok {
+		return "", false
+	}
+	suffix := D.PublicSuffix(host)
+	if len(suffix) == len(host) {
+		return "", false
+	}
+
+	labels := strings.Split(strings.TrimSuffix(host, labelSeparator+suffix), labelSeparator)
+	return labels[len(labels)-1] + labelSeparator + suffix, true
+}
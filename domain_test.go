@@ -0,0 +1,153 @@
+package qradix
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestDomainTable(t *testing.T) {
+	t.Run("test LookupHost", testLookupHost)
+	t.Run("test PublicSuffix and RegistrableDomain", testPublicSuffix)
+	t.Run("test LookupHost with random domains", testLookupHostWithRandomDomains)
+}
+
+func testLookupHost(t *testing.T) {
+	table := NewDomainTable()
+	for _, domain := range []string{"example.com", "*.example.com", "*.b.example.com", "a.b.example.com", "Other.ORG."} {
+		if _, err := table.Insert(domain, domain); err != nil {
+			t.Fatalf("Insert(%s): %s", domain, err)
+		}
+	}
+	for _, domain := range []string{"", ".", "a..com", "a.*.com", "*", "*.", "a*.com"} {
+		if _, err := table.Insert(domain, domain); err != ErrInvalidDomain {
+			t.Errorf("Insert(%s): should be invalid", domain)
+		}
+	}
+
+	cases := map[string]string{
+		"example.com":           "example.com",
+		"EXAMPLE.com.":          "example.com",
+		"www.example.com":       "*.example.com",
+		"b.example.com":         "*.example.com",
+		"a.b.example.com":       "a.b.example.com",
+		"c.b.example.com":       "*.b.example.com",
+		"x.c.b.example.com":     "*.b.example.com",
+		"x.a.b.example.com":     "*.b.example.com",
+		"other.org":             "other.org",
+		"www.other.org":         "",
+		"com":                   "",
+		"notexample.com":        "",
+		"example.com.cn":        "",
+		"a..example.com":        "",
+		"*.example.com":         "",
+		"www.sub.example.com":   "*.example.com",
+		"www.sub.example.co.uk": "",
+	}
+	for host, expected := range cases {
+		rule, val, ok := table.LookupHost(host)
+		if rule != expected || ok != (expected != "") || ok && val.(string) == "" {
+			t.Errorf("LookupHost(%s): got %s %v %t expect %s", host, rule, val, ok, expected)
+		}
+	}
+
+	if !table.Remove("*.example.com") || table.Remove("*.example.com") || table.Size() != 4 {
+		t.Fatal("Remove: failed to remove")
+	}
+	if rule, _, ok := table.LookupHost("www.example.com"); ok {
+		t.Fatalf("LookupHost: got %s after removing", rule)
+	}
+}
+
+func testPublicSuffix(t *testing.T) {
+	table := NewDomainTable()
+	for _, rule := range []string{"com", "uk", "co.uk", "*.ck", "!www.ck", "jp", "*.kobe.jp", "!city.kobe.jp"} {
+		if err := table.AddPublicSuffix(rule); err != nil {
+			t.Fatalf("AddPublicSuffix(%s): %s", rule, err)
+		}
+	}
+	if err := table.AddPublicSuffix("!ck"); err != ErrInvalidDomain {
+		t.Fatal("AddPublicSuffix: exception with one label should be invalid")
+	}
+
+	cases := []struct {
+		host        string
+		suffix      string
+		registrable string
+	}{
+		{"com", "com", ""},
+		{"example.com", "com", "example.com"},
+		{"www.example.com", "com", "example.com"},
+		{"example.co.uk", "co.uk", "example.co.uk"},
+		{"a.b.example.co.uk", "co.uk", "example.co.uk"},
+		{"co.uk", "co.uk", ""},
+		{"ck", "ck", ""},
+		{"test.ck", "test.ck", ""},
+		{"b.test.ck", "test.ck", "b.test.ck"},
+		{"www.ck", "ck", "www.ck"},
+		{"www.www.ck", "ck", "www.ck"},
+		{"city.kobe.jp", "kobe.jp", "city.kobe.jp"},
+		{"a.b.kobe.jp", "b.kobe.jp", "a.b.kobe.jp"},
+		{"example.test", "test", "example.test"},
+		{"WWW.Example.COM.", "com", "example.com"},
+		{"", "", ""},
+	}
+	for _, tc := range cases {
+		if got := table.PublicSuffix(tc.host); got != tc.suffix {
+			t.Errorf("PublicSuffix(%s): got %s expect %s", tc.host, got, tc.suffix)
+		}
+		got, ok := table.RegistrableDomain(tc.host)
+		if got != tc.registrable || ok != (tc.registrable != "") {
+			t.Errorf("RegistrableDomain(%s): got %s %t expect %s", tc.host, got, ok, tc.registrable)
+		}
+	}
+}
+
+func randomDomain(labelCount int) string {
+	labels := []string{}
+	for i := 0; i < labelCount; i++ {
+		labels = append(labels, []string{"a", "b", "ab", "世界"}[rand.Intn(4)])
+	}
+	return strings.Join(labels, ".")
+}
+
+func testLookupHostWithRandomDomains(t *testing.T) {
+	seedRand()
+	for i := 0; i < *testRound; i++ {
+		table := NewDomainTable()
+		domains := map[string]bool{}
+		for j := 0; j < *actionCount; j++ {
+			domain := randomDomain(rand.Intn(4) + 1)
+			if rand.Intn(2) == 0 {
+				domain = "*." + domain
+			}
+			if rand.Intn(4) == 0 {
+				table.Remove(domain)
+				delete(domains, domain)
+			} else {
+				table.Insert(domain, domain)
+				domains[domain] = true
+			}
+		}
+
+		for j := 0; j < *actionCount; j++ {
+			host := randomDomain(rand.Intn(5) + 1)
+			expected := ""
+			if domains[host] {
+				expected = host
+			} else {
+				for domain := range domains {
+					if strings.HasPrefix(domain, "*.") &&
+						strings.HasSuffix(host, domain[1:]) &&
+						len(domain) > len(expected) {
+						expected = domain
+					}
+				}
+			}
+
+			if rule, val, ok := table.LookupHost(host); rule != expected || ok && val.(string) != rule {
+				t.Fatalf("LookupHost(%s): got %s expect %s, seed: %d", host, rule, expected, *seed)
+			}
+		}
+	}
+}
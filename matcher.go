@@ -0,0 +1,200 @@
+package qradix
+
+import (
+	"io"
+	"strings"
+)
+
+// Match is an occurrence of a stored key in a text
+type Match struct {
+	Start int // Start is the byte offset where the key starts in the text
+	End   int // End is the byte offset where the key ends in the text
+	Key   string
+	Val   interface{}
+}
+
+// acState is a state of the Aho-Corasick automaton,
+// every rune along the edges of the tree is a state.
+type acState struct {
+	// a state usually has only one transition (in the middle of a node),
+	// so the first transition is stored in fields and the others are stored in the map
+	nextRune  rune
+	nextState int
+	next      map[rune]int
+	fail      int // fail is the state of the longest proper suffix which is also along the edges
+	output    int // output is the index of the key ending at this state, it is -1 if there is no key
+	dict      int // dict is the nearest state with a key by following fail links, it is 0 if there is none
+	depth     int // depth is the number of runes from the root state
+}
+
+// Matcher finds all occurrences of all keys of a tree in a text in a single pass.
+// It is built from a snapshot of the tree, so later modifications on the tree are not visible to it.
+// Matcher is safe for concurrent use.
+type Matcher struct {
+	states   []*acState
+	keys     []KV
+	maxDepth int
+}
+
+// NewMatcher builds an Aho-Corasick automaton with failure links and output links from the tree
+func NewMatcher(tree *RTree) *Matcher {
+	tree.m.RLock()
+	defer tree.m.RUnlock()
+
+	m := &Matcher{
+		states: []*acState{&acState{output: -1}},
+		keys:   []KV{},
+	}
+	for n := tree.root; n != nil; n = n.Next {
+		m.addNode(n, 0, "")
+	}
+	m.link()
+	return m
+}
+
+// addNode adds states for node n which starts from state from
+func (m *Matcher) addNode(n *node, from int, base string) {
+	state := from
+	for _, r := range n.Prefix {
+		depth := m.states[state].depth + 1
+		if depth > m.maxDepth {
+			m.maxDepth = depth
+		}
+		m.states = append(m.states, &acState{output: -1, depth: depth})
+		next := len(m.states) - 1
+		m.addTransition(state, r, next)
+		state = next
+	}
+
+	key := base + n.Prefix
+	if n.Leaf != nil {
+		m.keys = append(m.keys, KV{Key: key, Val: n.Leaf.Val})
+		m.states[state].output = len(m.keys) - 1
+	}
+	for child := n.Children; child != nil; child = child.Next {
+		m.addNode(child, state, key)
+	}
+}
+
+func (m *Matcher) addTransition(from int, r rune, to int) {
+	s := m.states[from]
+	if s.nextState == 0 {
+		s.nextRune, s.nextState = r, to
+		return
+	}
+	if s.next == nil {
+		s.next = map[rune]int{}
+	}
+	s.next[r] = to
+}
+
+// goTo returns the state transited from the state with the rune r
+func (m *Matcher) goTo(from int, r rune) (int, bool) {
+	s := m.states[from]
+	if s.nextState != 0 && s.nextRune == r {
+		return s.nextState, true
+	}
+	to, ok := s.next[r]
+	return to, ok
+}
+
+// transitions calls fn with all transitions of the state
+func (m *Matcher) transitions(from int, fn func(r rune, to int)) {
+	s := m.states[from]
+	if s.nextState != 0 {
+		fn(s.nextRune, s.nextState)
+	}
+	for r, to := range s.next {
+		fn(r, to)
+	}
+}
+
+// link computes failure links and output links in breadth first order
+func (m *Matcher) link() {
+	queue := []int{}
+	m.transitions(0, func(r rune, to int) {
+		queue = append(queue, to)
+	})
+
+	for len(queue) > 0 {
+		from := queue[0]
+		queue = queue[1:]
+
+		m.transitions(from, func(r rune, to int) {
+			queue = append(queue, to)
+
+			fail := m.states[from].fail
+			for {
+				if next, ok := m.goTo(fail, r); ok {
+					m.states[to].fail = next
+					break
+				} else if fail == 0 {
+					break
+				}
+				fail = m.states[fail].fail
+			}
+
+			failState := m.states[m.states[to].fail]
+			if failState.output >= 0 {
+				m.states[to].dict = m.states[to].fail
+			} else {
+				m.states[to].dict = failState.dict
+			}
+		})
+	}
+}
+
+// FindAll returns all occurrences of all keys in the text,
+// they are sorted by end offsets, and longer ones come first if they end at the same offset.
+func (m *Matcher) FindAll(text string) []Match {
+	matches := []Match{}
+	m.Scan(strings.NewReader(text), func(match Match) bool {
+		matches = append(matches, match)
+		return true
+	})
+	return matches
+}
+
+// Scan reads runes from the reader until io.EOF and calls fn with every occurrence of every key,
+// in the same order as FindAll. It stops if fn returns false.
+func (m *Matcher) Scan(reader io.RuneReader, fn func(Match) bool) error {
+	// starts keeps the start offsets of the last runes,
+	// the sizes of invalid bytes read as utf8.RuneError are different from the size of the rune in keys,
+	// so start offsets of matches are found by the numbers of runes instead of lengths of keys
+	starts := make([]int, m.maxDepth+1)
+	state, offset := 0, 0
+	for count := 0; ; count++ {
+		r, size, err := reader.ReadRune()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		starts[count%len(starts)] = offset
+		offset += size
+
+		for {
+			if next, ok := m.goTo(state, r); ok {
+				state = next
+				break
+			} else if state == 0 {
+				break
+			}
+			state = m.states[state].fail
+		}
+
+		output := state
+		if m.states[output].output < 0 {
+			output = m.states[output].dict
+		}
+		for output != 0 {
+			kv := m.keys[m.states[output].output]
+			start := starts[(count-m.states[output].depth+1)%len(starts)]
+			match := Match{Start: start, End: offset, Key: kv.Key, Val: kv.Val}
+			if !fn(match) {
+				return nil
+			}
+			output = m.states[output].dict
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package qradix
+
+import (
+	"bufio"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestMatcher(t *testing.T) {
+	t.Run("test FindAll", testMatcherFindAll)
+	t.Run("test Scan", testMatcherScan)
+	t.Run("test FindAll with random keys", testMatcherWithRandomKeys)
+}
+
+func testMatcherFindAll(t *testing.T) {
+	tree := NewRTree()
+	for _, key := range []string{"he", "she", "his", "hers", "中文", "文字"} {
+		tree.Insert(key, key)
+	}
+	matcher := NewMatcher(tree)
+
+	expected := []Match{
+		Match{Start: 1, End: 4, Key: "she"},
+		Match{Start: 2, End: 4, Key: "he"},
+		Match{Start: 2, End: 6, Key: "hers"},
+		Match{Start: 6, End: 12, Key: "中文"},
+		Match{Start: 9, End: 15, Key: "文字"},
+	}
+	matches := matcher.FindAll("ushers中文字")
+	if len(matches) != len(expected) {
+		t.Fatalf("FindAll: got %+v expect %+v", matches, expected)
+	}
+	for i, match := range matches {
+		if match.Start != expected[i].Start ||
+			match.End != expected[i].End ||
+			match.Key != expected[i].Key ||
+			match.Val.(string) != expected[i].Key {
+			t.Fatalf("FindAll: got %+v expect %+v", match, expected[i])
+		}
+	}
+
+	// the matcher is a snapshot
+	tree.Insert("us", "us")
+	if matches = matcher.FindAll("us"); len(matches) != 0 {
+		t.Fatalf("FindAll: got %+v from a snapshot", matches)
+	}
+	if matches = NewMatcher(NewRTree()).FindAll("ushers"); len(matches) != 0 {
+		t.Fatalf("FindAll: got %+v from an empty tree", matches)
+	}
+
+	// invalid bytes are read as utf8.RuneError, whose size is different from the size in keys
+	tree = NewRTree()
+	for _, key := range []string{"\uFFFD", "a\uFFFDb"} {
+		tree.Insert(key, key)
+	}
+	expected = []Match{
+		Match{Start: 0, End: 1, Key: "\uFFFD"},
+		Match{Start: 2, End: 3, Key: "\uFFFD"},
+		Match{Start: 1, End: 4, Key: "a\uFFFDb"},
+	}
+	matches = NewMatcher(tree).FindAll("\xffa\xfeb")
+	if len(matches) != len(expected) {
+		t.Fatalf("FindAll: got %+v expect %+v", matches, expected)
+	}
+	for i, match := range matches {
+		if match.Start != expected[i].Start || match.End != expected[i].End || match.Key != expected[i].Key {
+			t.Fatalf("FindAll: got %+v expect %+v", match, expected[i])
+		}
+	}
+}
+
+func testMatcherScan(t *testing.T) {
+	tree := NewRTree()
+	for _, key := range []string{"a", "aa"} {
+		tree.Insert(key, key)
+	}
+	matcher := NewMatcher(tree)
+
+	count := 0
+	err := matcher.Scan(bufio.NewReader(strings.NewReader("aaaa")), func(match Match) bool {
+		count++
+		return count < 3
+	})
+	if err != nil || count != 3 {
+		t.Fatalf("Scan: it should stop after 3 matches: %d %v", count, err)
+	}
+}
+
+func testMatcherWithRandomKeys(t *testing.T) {
+	seedRand()
+	for i := 0; i < *testRound; i++ {
+		var actions []string
+		tree := NewRTree()
+		dict := make(map[string]string)
+		randomStrings := GetTestStrings()
+
+		for j := 0; j < *actionCount; j++ {
+			doRandomAction(&actions, randomStrings[rand.Intn(len(randomStrings))], tree, dict)
+		}
+		matcher := NewMatcher(tree)
+
+		text := ""
+		for j := 0; j < 10; j++ {
+			text += randomStrings[rand.Intn(len(randomStrings))]
+		}
+
+		// every occurrence found by scanning each offset
+		expected := map[Match]bool{}
+		for start := range text {
+			for _, match := range tree.PrefixMatches(text[start:]) {
+				expected[Match{Start: start, End: start + len(match.Key), Key: match.Key}] = true
+			}
+		}
+
+		matches := matcher.FindAll(text)
+		for j, match := range matches {
+			if j > 0 && match.End < matches[j-1].End {
+				t.Fatalf("FindAll: matches are not sorted by end offsets, seed: %d", *seed)
+			}
+			if match.Val.(string) != dict[match.Key] || text[match.Start:match.End] != match.Key {
+				t.Fatalf("FindAll: invalid match %+v, seed: %d", match, *seed)
+			}
+			match.Val = nil
+			if !expected[match] {
+				printActions(actions)
+				printRTree(tree)
+				t.Fatalf("FindAll: unexpected match %+v, seed: %d", match, *seed)
+			}
+			delete(expected, match)
+		}
+		if len(expected) > 0 {
+			printActions(actions)
+			printRTree(tree)
+			t.Fatalf("FindAll: missing matches %+v in %s, seed: %d", expected, text, *seed)
+		}
+	}
+}
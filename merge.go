@@ -0,0 +1,107 @@
+package qradix
+
+// Merge adds all keys of other into T.
+// Both trees are walked in lockstep, so shared prefixes are split at most once instead of once per key.
+// If a key exists in both trees, the value returned by resolve is kept,
+// resolve is called with the key, T's value and other's value.
+// If resolve is nil, other's value is kept.
+// other is not modified, its nodes are copied but its values are shared.
+func (T *RTree) Merge(other *RTree, resolve func(key string, mine, theirs interface{}) interface{}) {
+	if other == nil || other == T {
+		return
+	}
+	unlock := lockPair(T, other, true)
+	defer unlock()
+	T.version++
+
+	for src := other.root; src != nil; src = src.Next {
+		T.size += T.mergeNode(nil, "", src, src.Prefix, resolve)
+	}
+	T.refresh()
+}
+
+// mergeNode merges node src, whose prefix is replaced by prefix, into the level under parent.
+// base is the key of parent and parent is nil at the first level.
+// it returns the number of keys added, and the count of parent should be increased by it.
+func (T *RTree) mergeNode(
+	parent *node,
+	base string,
+	src *node,
+	prefix string,
+	resolve func(key string, mine, theirs interface{}) interface{},
+) int {
+	first := T.root
+	if parent != nil {
+		first = parent.Children
+	}
+
+	var dst *node
+	if first != nil {
+		dst = first.Idx[getRune1(prefix)]
+	}
+	if dst == nil {
+		// no key in T shares the prefix, src is copied as a whole
+		T.attach(parent, copyNode(src, prefix))
+		return src.count
+	}
+
+	dst.fresh = false
+	offset := commonPrefixOffset(dst.Prefix, prefix)
+	if offset == -1 {
+		// this is impossible
+		panic(errImpossible(dst.Prefix, prefix))
+	} else if offset < len(dst.Prefix)-1 {
+		// then dst.Prefix is the common prefix
+		split(dst, offset+1)
+	}
+
+	key := base + dst.Prefix
+	if len(dst.Prefix) < len(prefix) {
+		// src is longer, merge the rest of it into dst's children
+		added := T.mergeNode(dst, key, src, prefix[len(dst.Prefix):], resolve)
+		dst.count += added
+		return added
+	}
+
+	// dst and src have the same prefix now
+	added := 0
+	if src.Leaf != nil {
+		if dst.Leaf == nil {
+			dst.Leaf = &leafNode{Val: src.Leaf.Val}
+			added++
+		} else if resolve != nil {
+			dst.Leaf.Val = resolve(key, dst.Leaf.Val, src.Leaf.Val)
+		} else {
+			dst.Leaf.Val = src.Leaf.Val
+		}
+	}
+	for child := src.Children; child != nil; child = child.Next {
+		added += T.mergeNode(dst, key, child, child.Prefix, resolve)
+	}
+	dst.count += added
+	merge(dst, dst.Children)
+	return added
+}
+
+// copyNode deeply copies node n and its descendants, and the copy's prefix is set to prefix.
+// n's siblings are not copied.
+func copyNode(n *node, prefix string) *node {
+	copied := &node{Prefix: prefix, count: n.count}
+	if n.Leaf != nil {
+		copied.Leaf = &leafNode{Val: n.Leaf.Val}
+	}
+
+	var last *node
+	for child := n.Children; child != nil; child = child.Next {
+		copiedChild := copyNode(child, child.Prefix)
+		if last == nil {
+			copied.Children = copiedChild
+			copiedChild.Idx = map[rune]*node{}
+		} else {
+			last.Next = copiedChild
+		}
+		copied.Children.Idx[getRune1(copiedChild.Prefix)] = copiedChild
+		last = copiedChild
+	}
+	return copied
+}
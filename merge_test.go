@@ -0,0 +1,153 @@
+package qradix
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	t.Run("test Merge", testMerge)
+	t.Run("test Merge with random keys", testMergeWithRandomKeys)
+	t.Run("test Merge concurrently", testMergeConcurrently)
+}
+
+func testMerge(t *testing.T) {
+	type TestCase struct {
+		desc   string
+		mine   []string
+		theirs []string
+		expect map[string]string
+	}
+
+	// values are "mine" or "theirs", and conflicts are resolved by joining them
+	testCases := []*TestCase{
+		&TestCase{
+			desc:   "disjoint trees",
+			mine:   []string{"a", "ab"},
+			theirs: []string{"b", "ba"},
+			expect: map[string]string{"a": "mine", "ab": "mine", "b": "theirs", "ba": "theirs"},
+		},
+		&TestCase{
+			desc:   "shared prefixes are split",
+			mine:   []string{"abc", "abd"},
+			theirs: []string{"ab", "abe", "ax"},
+			expect: map[string]string{"abc": "mine", "abd": "mine", "ab": "theirs", "abe": "theirs", "ax": "theirs"},
+		},
+		&TestCase{
+			desc:   "conflicts are resolved",
+			mine:   []string{"a", "abc", "中文"},
+			theirs: []string{"abc", "中文", "中国"},
+			expect: map[string]string{"a": "mine", "abc": "mine+theirs", "中文": "mine+theirs", "中国": "theirs"},
+		},
+		&TestCase{
+			desc:   "merge into an empty tree",
+			mine:   []string{},
+			theirs: []string{"a", "ab"},
+			expect: map[string]string{"a": "theirs", "ab": "theirs"},
+		},
+	}
+
+	resolve := func(key string, mine, theirs interface{}) interface{} {
+		return mine.(string) + "+" + theirs.(string)
+	}
+	for _, tc := range testCases {
+		mine, theirs := NewRTree(), NewRTree()
+		for _, key := range tc.mine {
+			mine.Insert(key, "mine")
+		}
+		for _, key := range tc.theirs {
+			theirs.Insert(key, "theirs")
+		}
+
+		mine.Merge(theirs, resolve)
+		if mine.Size() != len(tc.expect) {
+			t.Errorf("Merge(%s): size got %d expect %d", tc.desc, mine.Size(), len(tc.expect))
+		}
+		for key, expected := range tc.expect {
+			val, err := mine.Get(key)
+			if err != nil {
+				t.Errorf("Merge(%s): %s not found: %s", tc.desc, key, err)
+			} else if val.(string) != expected {
+				t.Errorf("Merge(%s): %s got %s expect %s", tc.desc, key, val, expected)
+			}
+		}
+		if theirs.Size() != len(tc.theirs) {
+			t.Errorf("Merge(%s): the other tree should not be changed", tc.desc)
+		}
+	}
+}
+
+func testMergeWithRandomKeys(t *testing.T) {
+	seedRand()
+	for i := 0; i < *testRound; i++ {
+		var actions []string
+		mine, theirs := NewRTree(), NewRTree()
+		dict := make(map[string]string)
+		randomStrings := GetTestStrings()
+
+		for j := 0; j < *actionCount; j++ {
+			doRandomAction(&actions, randomStrings[rand.Intn(len(randomStrings))], mine, dict)
+			doRandomAction(&actions, randomStrings[rand.Intn(len(randomStrings))], theirs, nil)
+		}
+		BFS(theirs, func(n Node) {
+			if val, ok := n.Value(); ok {
+				dict[val.(string)] = val.(string)
+			}
+		})
+
+		mine.Merge(theirs, nil)
+		if !isEqual(mine, dict) || mine.Size() != len(dict) {
+			printActions(actions)
+			printRTree(mine)
+			printMap(dict)
+			t.Fatalf("merged tree is not identical to Map, seed: %d", *seed)
+		}
+	}
+}
+
+func testMergeConcurrently(t *testing.T) {
+	a, b := NewRTree(), NewRTree()
+	for _, key := range GetTestStrings() {
+		a.Insert("a"+key, key)
+		b.Insert("b"+key, key)
+	}
+	size := a.Size() + b.Size() + 1
+
+	// writers are queued while trees are merged into each other
+	wg := &sync.WaitGroup{}
+	for _, pair := range [][2]*RTree{{a, b}, {b, a}} {
+		mine, theirs := pair[0], pair[1]
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				mine.Merge(theirs, nil)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				theirs.Insert("c", "c")
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Merge is deadlocked")
+	}
+
+	for _, tree := range []*RTree{a, b} {
+		if tree.Size() != size {
+			t.Fatalf("Merge: size got %d expect %d", tree.Size(), size)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package qradix
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"strings"
+)
+
+var (
+	ErrHashingDisabled = errors.New("hashing is not enabled")
+)
+
+const (
+	nodeHashTag  byte = 0
+	levelHashTag byte = 1
+)
+
+// ValueHasher returns the bytes which represent a value in the hash of its node,
+// equal values must be hashed into equal bytes.
+type ValueHasher func(val interface{}) []byte
+
+// StringValueHasher hashes string values, it panics if the value is not a string
+func StringValueHasher(val interface{}) []byte {
+	return []byte(val.(string))
+}
+
+// EnableHashing makes every node maintain a hash of its prefix, its value and its children,
+// then hashes are updated incrementally along the changed path after each modification.
+// Hashes only depend on stored keys and values, so trees with same keys and values have the same RootHash.
+// Hashing is disabled if hashValue is nil.
+func (T *RTree) EnableHashing(hashValue ValueHasher) {
+	T.m.Lock()
+	defer T.m.Unlock()
+
+	T.hashValue = hashValue
+	// hashes are outdated if hashing was disabled
+	T.markAll()
+	T.refresh()
+}
+
+// levelHashes returns hashes of the level sorted by prefixes
+func levelHashes(first *node) [][]byte {
+	hashes := [][]byte{}
+	for _, n := range sortedLevel(first) {
+		hashes = append(hashes, n.hash)
+	}
+	return hashes
+}
+
+func writeBytes(h hash.Hash, data []byte) {
+	buf := make([]byte, binary.MaxVarintLen64)
+	h.Write(buf[:binary.PutUvarint(buf, uint64(len(data)))])
+	h.Write(data)
+}
+
+// hashNode returns the hash of a node, levelHash is the hash of the node's children
+func hashNode(prefix string, hasLeaf bool, valueHash []byte, levelHash []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{nodeHashTag})
+	writeBytes(h, []byte(prefix))
+	if hasLeaf {
+		h.Write([]byte{1})
+		writeBytes(h, valueHash)
+	} else {
+		h.Write([]byte{0})
+	}
+	h.Write(levelHash)
+	return h.Sum(nil)
+}
+
+// hashLevel returns the hash of a level from its nodes' hashes sorted by prefixes
+func hashLevel(hashes [][]byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{levelHashTag})
+	for _, nodeHash := range hashes {
+		h.Write(nodeHash)
+	}
+	return h.Sum(nil)
+}
+
+// RootHash returns the hash of the whole tree, it returns nil if hashing is not enabled
+func (T *RTree) RootHash() []byte {
+	T.m.RLock()
+	defer T.m.RUnlock()
+
+	if T.hashValue == nil {
+		return nil
+	}
+	return hashLevel(levelHashes(T.root))
+}
+
+// ProofStep is a node on the path from the first level to the key
+type ProofStep struct {
+	Segment   string
+	HasLeaf   bool
+	ValueHash []byte   // ValueHash is the hashed value of the node, it is empty in the last step
+	Siblings  [][]byte // Siblings are hashes of other nodes in the same level sorted by prefixes
+	Index     int      // Index is the position of the node among its sorted siblings
+}
+
+// Proof proves that a key and its value are stored in a tree with a specific RootHash
+type Proof struct {
+	Steps    []*ProofStep
+	Children []byte // Children is the hash of the children of the key's node
+}
+
+// ProofFor returns the proof of the key which can be verified by VerifyProof
+func (T *RTree) ProofFor(key string) (*Proof, error) {
+	T.m.RLock()
+	defer T.m.RUnlock()
+
+	if T.hashValue == nil {
+		return nil, ErrHashingDisabled
+	} else if len(key) == 0 {
+		return nil, ErrEmptyKey
+	}
+
+	proof := &Proof{Steps: []*ProofStep{}}
+	var ok bool
+	var matchedNode *node
+	node1 := T.root
+	pathSuffix := key
+	for {
+		if node1 == nil {
+			return nil, ErrNotExist
+		}
+		matchedNode, ok = node1.Idx[getRune1(pathSuffix)]
+		if !ok {
+			return nil, ErrNotExist
+		}
+
+		step := &ProofStep{
+			Segment:  matchedNode.Prefix,
+			HasLeaf:  matchedNode.Leaf != nil,
+			Siblings: [][]byte{},
+		}
+		for i, n := range sortedLevel(node1) {
+			if n == matchedNode {
+				step.Index = i
+			} else {
+				step.Siblings = append(step.Siblings, n.hash)
+			}
+		}
+		proof.Steps = append(proof.Steps, step)
+
+		if !strings.HasPrefix(pathSuffix, matchedNode.Prefix) {
+			return nil, ErrNotExist
+		} else if len(pathSuffix) > len(matchedNode.Prefix) {
+			if matchedNode.Leaf != nil {
+				step.ValueHash = T.hashValue(matchedNode.Leaf.Val)
+			}
+			pathSuffix = pathSuffix[len(matchedNode.Prefix):]
+			node1 = matchedNode.Children
+			continue
+		} else if matchedNode.Leaf == nil {
+			return nil, ErrNotExist
+		}
+
+		proof.Children = hashLevel(levelHashes(matchedNode.Children))
+		return proof, nil
+	}
+}
+
+// VerifyProof checks if the key and the value are stored in the tree whose RootHash is rootHash,
+// hashValue must be same as the one used by the tree.
+func VerifyProof(rootHash []byte, key string, val interface{}, proof *Proof, hashValue ValueHasher) bool {
+	if proof == nil || len(proof.Steps) == 0 {
+		return false
+	}
+
+	// segments must compose the key
+	segments := ""
+	for _, step := range proof.Steps {
+		if len(step.Segment) == 0 || step.Index < 0 || step.Index > len(step.Siblings) {
+			return false
+		}
+		segments += step.Segment
+	}
+	if segments != key {
+		return false
+	}
+
+	last := proof.Steps[len(proof.Steps)-1]
+	if !last.HasLeaf {
+		return false
+	}
+	levelHash := []byte{}
+	nodeHash := hashNode(last.Segment, true, hashValue(val), proof.Children)
+	for i := len(proof.Steps) - 1; i >= 0; i-- {
+		step := proof.Steps[i]
+		if i < len(proof.Steps)-1 {
+			nodeHash = hashNode(step.Segment, step.HasLeaf, step.ValueHash, levelHash)
+		}
+
+		hashes := make([][]byte, 0, len(step.Siblings)+1)
+		hashes = append(hashes, step.Siblings[:step.Index]...)
+		hashes = append(hashes, nodeHash)
+		hashes = append(hashes, step.Siblings[step.Index:]...)
+		levelHash = hashLevel(hashes)
+	}
+	return bytes.Equal(levelHash, rootHash)
+}
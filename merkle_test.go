@@ -0,0 +1,122 @@
+package qradix
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestMerkle(t *testing.T) {
+	t.Run("test RootHash", testRootHash)
+	t.Run("test ProofFor and VerifyProof", testProof)
+	t.Run("test RootHash with random keys", testRootHashWithRandomKeys)
+}
+
+func testRootHash(t *testing.T) {
+	tree1, tree2 := NewRTree(), NewRTree()
+	if tree1.RootHash() != nil {
+		t.Fatal("RootHash should be nil if hashing is disabled")
+	}
+	tree1.EnableHashing(StringValueHasher)
+	tree2.EnableHashing(StringValueHasher)
+	if !bytes.Equal(tree1.RootHash(), tree2.RootHash()) {
+		t.Fatal("empty trees should have the same RootHash")
+	}
+
+	// same keys and values but different histories
+	for _, key := range []string{"a", "ab", "abc", "abd", "b", "中文"} {
+		tree1.Insert(key, key)
+	}
+	for _, key := range []string{"中文", "abd", "x", "b", "abc", "ab", "a", "abcd"} {
+		tree2.Insert(key, key)
+	}
+	tree2.Remove("x")
+	tree2.RemovePrefix("abcd")
+	if !bytes.Equal(tree1.RootHash(), tree2.RootHash()) {
+		printRTree(tree1)
+		printRTree(tree2)
+		t.Fatal("trees with same keys and values should have the same RootHash")
+	}
+
+	tree2.Insert("ab", "changed")
+	if bytes.Equal(tree1.RootHash(), tree2.RootHash()) {
+		t.Fatal("trees with different values should have different RootHashes")
+	}
+	tree1.Apply(Diff(tree1, tree2))
+	if !bytes.Equal(tree1.RootHash(), tree2.RootHash()) {
+		t.Fatal("patched tree should have the same RootHash")
+	}
+}
+
+func testProof(t *testing.T) {
+	tree := NewRTree()
+	if _, err := tree.ProofFor("a"); err != ErrHashingDisabled {
+		t.Fatalf("ProofFor: hashing should be disabled: %s", err)
+	}
+
+	keys := []string{"a", "ab", "abc", "abd", "b", "中文", "中国"}
+	for _, key := range keys {
+		tree.Insert(key, key)
+	}
+	tree.EnableHashing(StringValueHasher)
+	rootHash := tree.RootHash()
+
+	for _, key := range keys {
+		proof, err := tree.ProofFor(key)
+		if err != nil {
+			t.Fatalf("ProofFor(%s): %s", key, err)
+		}
+		if !VerifyProof(rootHash, key, key, proof, StringValueHasher) {
+			t.Errorf("VerifyProof(%s): proof should be valid", key)
+		}
+		if VerifyProof(rootHash, key, "forged", proof, StringValueHasher) {
+			t.Errorf("VerifyProof(%s): forged value should not be verified", key)
+		}
+	}
+
+	proof, _ := tree.ProofFor("ab")
+	if VerifyProof(rootHash, "abd", "abd", proof, StringValueHasher) {
+		t.Error("VerifyProof: proof of another key should not be verified")
+	}
+	for _, key := range []string{"x", "abcd", "中"} {
+		if _, err := tree.ProofFor(key); err != ErrNotExist {
+			t.Errorf("ProofFor(%s): key should not exist: %s", key, err)
+		}
+	}
+}
+
+func testRootHashWithRandomKeys(t *testing.T) {
+	seedRand()
+	for i := 0; i < *testRound; i++ {
+		var actions []string
+		tree := NewRTree()
+		tree.EnableHashing(StringValueHasher)
+		dict := make(map[string]string)
+		randomStrings := GetTestStrings()
+
+		for j := 0; j < *actionCount; j++ {
+			doRandomAction(&actions, randomStrings[rand.Intn(len(randomStrings))], tree, dict)
+		}
+
+		// hashes maintained incrementally should be same as the hashes of a new tree
+		builder := NewBuilder()
+		for key, val := range dict {
+			builder.Add(key, val)
+		}
+		built := builder.Build()
+		built.EnableHashing(StringValueHasher)
+		if !bytes.Equal(tree.RootHash(), built.RootHash()) {
+			printActions(actions)
+			printRTree(tree)
+			printRTree(built)
+			t.Fatalf("RootHash is not identical to the built tree's, seed: %d", *seed)
+		}
+
+		for key := range dict {
+			proof, err := tree.ProofFor(key)
+			if err != nil || !VerifyProof(tree.RootHash(), key, key, proof, StringValueHasher) {
+				t.Fatalf("ProofFor(%s) is not valid (%v), seed: %d", key, err, *seed)
+			}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package qradix
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrOutOfRange = errors.New("index out of range")
+)
+
+// CountPrefix returns the number of keys which start with the prefix in O(depth)
+func (T *RTree) CountPrefix(prefix string) int {
+	T.m.RLock()
+	defer T.m.RUnlock()
+
+	return T.countPrefix(prefix)
+}
+
+func (T *RTree) countPrefix(prefix string) int {
+	if len(prefix) == 0 {
+		return T.size
+	}
+
+	children := T.root
+	pathSuffix := prefix
+	for children != nil {
+		rune1, _ := utf8.DecodeRuneInString(pathSuffix)
+		matchedNode, ok := children.Idx[rune1]
+		if !ok {
+			return 0
+		}
+
+		if strings.HasPrefix(matchedNode.Prefix, pathSuffix) {
+			// all keys under matchedNode start with the prefix
+			return matchedNode.count
+		} else if !strings.HasPrefix(pathSuffix, matchedNode.Prefix) {
+			return 0
+		}
+		pathSuffix = pathSuffix[len(matchedNode.Prefix):]
+		children = matchedNode.Children
+	}
+	return 0
+}
+
+// Rank returns the number of keys which are less than the key in lexicographic order,
+// the key is not necessary to be stored in the tree.
+func (T *RTree) Rank(key string) int {
+	T.m.RLock()
+	defer T.m.RUnlock()
+
+	return T.rank(key)
+}
+
+func (T *RTree) rank(key string) int {
+	rank := 0
+	children := T.root
+	pathSuffix := key
+	for children != nil && len(pathSuffix) > 0 {
+		rune1, _ := utf8.DecodeRuneInString(pathSuffix)
+		var matchedNode *node
+		for n := children; n != nil; n = n.Next {
+			siblingRune1, _ := utf8.DecodeRuneInString(n.Prefix)
+			if siblingRune1 < rune1 {
+				rank += n.count
+			} else if siblingRune1 == rune1 {
+				matchedNode = n
+			}
+		}
+		if matchedNode == nil {
+			return rank
+		}
+
+		if !strings.HasPrefix(pathSuffix, matchedNode.Prefix) {
+			// keys under matchedNode are all less or all greater than the key
+			if matchedNode.Prefix < pathSuffix {
+				rank += matchedNode.count
+			}
+			return rank
+		}
+		if matchedNode.Leaf != nil && len(pathSuffix) > len(matchedNode.Prefix) {
+			// matchedNode's key is a prefix of the key
+			rank++
+		}
+		pathSuffix = pathSuffix[len(matchedNode.Prefix):]
+		children = matchedNode.Children
+	}
+	return rank
+}
+
+// Select returns the i-th (starting from 0) key and its value in lexicographic order,
+// it returns ErrOutOfRange if i is not in [0, Size()).
+func (T *RTree) Select(i int) (string, interface{}, error) {
+	T.m.RLock()
+	defer T.m.RUnlock()
+
+	return T.selectKey(i)
+}
+
+func (T *RTree) selectKey(i int) (string, interface{}, error) {
+	if i < 0 || i >= T.size {
+		return "", nil, ErrOutOfRange
+	}
+
+	key := ""
+	children := T.root
+	for children != nil {
+		var matchedNode *node
+		for _, n := range sortedLevel(children) {
+			if i < n.count {
+				matchedNode = n
+				break
+			}
+			i -= n.count
+		}
+		if matchedNode == nil {
+			break
+		}
+
+		key += matchedNode.Prefix
+		if matchedNode.Leaf != nil {
+			if i == 0 {
+				return key, matchedNode.Leaf.Val, nil
+			}
+			i--
+		}
+		children = matchedNode.Children
+	}
+
+	// this is impossible if counts are correct
+	return "", nil, ErrOutOfRange
+}
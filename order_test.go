@@ -0,0 +1,105 @@
+package qradix
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestOrderStatistics(t *testing.T) {
+	t.Run("test CountPrefix, Rank and Select", testOrderStatistics)
+	t.Run("test order statistics with random keys", testOrderStatisticsWithRandomKeys)
+}
+
+func testOrderStatistics(t *testing.T) {
+	tree := NewRTree()
+	keys := []string{"a", "ab", "abc", "abd", "b", "ba", "中国", "中文"}
+	for _, key := range keys {
+		tree.Insert(key, key)
+	}
+
+	counts := map[string]int{"": 8, "a": 4, "ab": 3, "abc": 1, "ac": 0, "b": 2, "中": 2, "中文字": 0}
+	for prefix, expected := range counts {
+		if got := tree.CountPrefix(prefix); got != expected {
+			t.Errorf("CountPrefix(%s): got %d expect %d", prefix, got, expected)
+		}
+	}
+
+	ranks := map[string]int{"": 0, "a": 0, "aa": 1, "ab": 1, "abb": 2, "abe": 4, "b": 4, "c": 6, "中": 6, "中文": 7, "中文字": 8}
+	for key, expected := range ranks {
+		if got := tree.Rank(key); got != expected {
+			t.Errorf("Rank(%s): got %d expect %d", key, got, expected)
+		}
+	}
+
+	for i, expected := range keys {
+		key, val, err := tree.Select(i)
+		if err != nil || key != expected || val.(string) != expected {
+			t.Errorf("Select(%d): got %s %v expect %s", i, key, err, expected)
+		}
+	}
+	for _, i := range []int{-1, len(keys)} {
+		if _, _, err := tree.Select(i); err != ErrOutOfRange {
+			t.Errorf("Select(%d): should be out of range", i)
+		}
+	}
+}
+
+func testOrderStatisticsWithRandomKeys(t *testing.T) {
+	seedRand()
+	for i := 0; i < *testRound; i++ {
+		var actions []string
+		tree := NewRTree()
+		dict := make(map[string]string)
+		randomStrings := GetTestStrings()
+
+		for j := 0; j < *actionCount; j++ {
+			doRandomAction(&actions, randomStrings[rand.Intn(len(randomStrings))], tree, dict)
+		}
+		if rand.Intn(2) == 0 {
+			prefix := randomStrings[rand.Intn(len(randomStrings))]
+			tree.RemovePrefix(prefix)
+			for key := range dict {
+				if strings.HasPrefix(key, prefix) {
+					delete(dict, key)
+				}
+			}
+		}
+
+		keys := []string{}
+		for key := range dict {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for j, expected := range keys {
+			key, _, err := tree.Select(j)
+			if err != nil || key != expected {
+				printActions(actions)
+				printRTree(tree)
+				t.Fatalf("Select(%d): got %s %v expect %s, seed: %d", j, key, err, expected, *seed)
+			}
+			if rank := tree.Rank(expected); rank != j {
+				t.Fatalf("Rank(%s): got %d expect %d, seed: %d", expected, rank, j, *seed)
+			}
+		}
+
+		for j := 0; j < *actionCount; j++ {
+			prefix := randomStrings[rand.Intn(len(randomStrings))]
+			prefix = prefix[:rand.Intn(len(prefix))+1]
+			expectedCount, expectedRank := 0, sort.SearchStrings(keys, prefix)
+			for _, key := range keys {
+				if strings.HasPrefix(key, prefix) {
+					expectedCount++
+				}
+			}
+			if count := tree.CountPrefix(prefix); count != expectedCount {
+				t.Fatalf("CountPrefix(%s): got %d expect %d, seed: %d", prefix, count, expectedCount, *seed)
+			}
+			if rank := tree.Rank(prefix); rank != expectedRank {
+				t.Fatalf("Rank(%s): got %d expect %d, seed: %d", prefix, rank, expectedRank, *seed)
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package qradix
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Page returns at most limit keys and values, which start with the prefix and are greater than after,
+// in lexicographic order.
+// To get the first page, after should be empty,
+// and nextCursor should be used as after to get the next page.
+// nextCursor is empty if there is no more page.
+// Keys inserted between calls are returned by later pages if they are greater than the cursor.
+func (T *RTree) Page(prefix string, after string, limit int) ([]KV, string) {
+	T.m.RLock()
+	defer T.m.RUnlock()
+
+	items := []KV{}
+	if limit <= 0 {
+		return items, ""
+	}
+
+	// one more item is collected to know if there is a next page
+	nodes, base := T.findPrefix(prefix)
+	for _, n := range nodes {
+		if !pageNode(n, base, after, limit+1, &items) {
+			break
+		}
+	}
+
+	if len(items) > limit {
+		items = items[:limit]
+		return items, items[limit-1].Key
+	}
+	return items, ""
+}
+
+// pageNode collects keys greater than after under node n in lexicographic order,
+// it returns false if there are already limit items
+func pageNode(n *node, base, after string, limit int, items *[]KV) bool {
+	key := base + n.Prefix
+	if key < after && !strings.HasPrefix(after, key) {
+		// all keys under n are less than after
+		return true
+	}
+
+	if n.Leaf != nil && key > after {
+		*items = append(*items, KV{Key: key, Val: n.Leaf.Val})
+		if len(*items) >= limit {
+			return false
+		}
+	}
+	for _, child := range sortedLevel(n.Children) {
+		if !pageNode(child, key, after, limit, items) {
+			return false
+		}
+	}
+	return true
+}
+
+// findPrefix returns nodes containing all keys starting with the prefix in lexicographic order,
+// and base is the key of their parent.
+func (T *RTree) findPrefix(prefix string) ([]*node, string) {
+	children := T.root
+	base := ""
+	pathSuffix := prefix
+	for len(pathSuffix) > 0 {
+		if children == nil {
+			return nil, ""
+		}
+		rune1, _ := utf8.DecodeRuneInString(pathSuffix)
+		matchedNode, ok := children.Idx[rune1]
+		if !ok {
+			return nil, ""
+		}
+
+		if strings.HasPrefix(matchedNode.Prefix, pathSuffix) {
+			// all keys under matchedNode start with the prefix
+			return []*node{matchedNode}, base
+		} else if !strings.HasPrefix(pathSuffix, matchedNode.Prefix) {
+			return nil, ""
+		}
+		base += matchedNode.Prefix
+		pathSuffix = pathSuffix[len(matchedNode.Prefix):]
+		children = matchedNode.Children
+	}
+
+	// the prefix is empty
+	return sortedLevel(children), base
+}
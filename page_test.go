@@ -0,0 +1,92 @@
+package qradix
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestPage(t *testing.T) {
+	t.Run("test Page", testPage)
+	t.Run("test Page with random keys", testPageWithRandomKeys)
+}
+
+func testPage(t *testing.T) {
+	tree := NewRTree()
+	for _, key := range []string{"b", "a/3", "a/1", "a/2", "a/21", "a", "中文"} {
+		tree.Insert(key, key)
+	}
+
+	// a/0 is inserted before the cursor and it is not returned
+	expectedPages := [][]string{{"a/1", "a/2"}, {"a/21", "a/22"}, {"a/3"}}
+	cursor := ""
+	for i, expected := range expectedPages {
+		items, next := tree.Page("a/", cursor, 2)
+		if len(items) != len(expected) {
+			t.Fatalf("Page(%d): got %+v expect %+v", i, items, expected)
+		}
+		for j, item := range items {
+			if item.Key != expected[j] || item.Val.(string) != expected[j] {
+				t.Errorf("Page(%d): got %+v expect %s", i, item, expected[j])
+			}
+		}
+		cursor = next
+		if i == 0 {
+			// keys inserted between calls
+			tree.Insert("a/0", "a/0")
+			tree.Insert("a/22", "a/22")
+		}
+	}
+	if cursor != "" {
+		t.Fatalf("Page: there should be no more page but got cursor %s", cursor)
+	}
+
+	if items, next := tree.Page("", "", 100); len(items) != 9 || next != "" {
+		t.Fatalf("Page: all keys should be returned %+v %s", items, next)
+	}
+}
+
+func testPageWithRandomKeys(t *testing.T) {
+	seedRand()
+	for i := 0; i < *testRound; i++ {
+		var actions []string
+		tree := NewRTree()
+		dict := make(map[string]string)
+		randomStrings := GetTestStrings()
+
+		for j := 0; j < *actionCount; j++ {
+			doRandomAction(&actions, randomStrings[rand.Intn(len(randomStrings))], tree, dict)
+		}
+
+		prefix := randomStrings[rand.Intn(len(randomStrings))]
+		prefix = prefix[:rand.Intn(len(prefix)+1)]
+		expected := []string{}
+		for key := range dict {
+			if strings.HasPrefix(key, prefix) {
+				expected = append(expected, key)
+			}
+		}
+		sort.Strings(expected)
+
+		got := []string{}
+		cursor := ""
+		limit := rand.Intn(5) + 1
+		for {
+			items, next := tree.Page(prefix, cursor, limit)
+			for _, item := range items {
+				got = append(got, item.Key)
+			}
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+
+		if strings.Join(got, ",") != strings.Join(expected, ",") {
+			printActions(actions)
+			printRTree(tree)
+			t.Fatalf("Page(%s): got %+v expect %+v, seed: %d", prefix, got, expected, *seed)
+		}
+	}
+}
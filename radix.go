@@ -3,14 +3,18 @@ package qradix
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"unicode/utf8"
 )
 
 var (
 	ErrEmptyKey     = errors.New("empty key is not allowed")
 	ErrNotExist     = errors.New("key not exist")
 	ErrInvalidSplit = errors.New("invalid split")
+	ErrInvalidKey   = errors.New("key is not valid UTF-8")
 	errImpossible   = func(prefix1, prefix2 string) string {
 		return fmt.Sprintf("the first rune of %s and %s must be same", prefix1, prefix2)
 	}
@@ -24,6 +28,11 @@ type node struct {
 	Leaf     *leafNode
 	// Idx finds the sibling with the first rune of the current key
 	Idx map[rune]*node
+	// fresh is false if the node or its descendants are changed after its summaries were computed
+	fresh bool
+	count int // count is the number of values under the node
+	hash  []byte
+	agg   interface{}
 }
 
 // Segment returns node's segment
@@ -60,21 +69,33 @@ type leafNode struct {
 }
 
 func newNode(prefix string, children *node, next *node, leaf *leafNode) *node {
-	return &node{
+	n := &node{
 		Prefix:   prefix,
 		Children: children,
 		Next:     next,
 		Leaf:     leaf,
 	}
+	n.count = countLeaves(children)
+	if leaf != nil {
+		n.count++
+	}
+	return n
 }
 
 // RTree is a radix tree
 type RTree struct {
-	root *node
-	size int
-	m    *sync.RWMutex
+	root       *node
+	size       int
+	m          *sync.RWMutex
+	hashValue  ValueHasher // nodes are not hashed if it is nil
+	aggregator Aggregator  // values are not aggregated if it is nil
+	id         uint64      // id orders locks of trees when two trees are locked together
+	version    uint64      // version is increased when nodes may be changed, so cursors kept out of locks are invalid
 }
 
+// treeCount is used for generating ids of trees
+var treeCount uint64
+
 // return common prefix's offset of s1 and s2, in byte
 // s1[:offset+1] == s2[:offset+1]
 func commonPrefixOffset(s1, s2 string) int {
@@ -101,6 +122,29 @@ func NewRTree() *RTree {
 	return &RTree{
 		root: nil,
 		m:    &sync.RWMutex{},
+		id:   atomic.AddUint64(&treeCount, 1),
+	}
+}
+
+// lockPair locks two different trees in the order of their ids,
+// so concurrent operations on the same pair of trees can't deadlock.
+// a is write locked if write is true and b is read locked.
+// It returns a function which unlocks both trees.
+func lockPair(a, b *RTree, write bool) func() {
+	lockA, unlockA := a.m.RLock, a.m.RUnlock
+	if write {
+		lockA, unlockA = a.m.Lock, a.m.Unlock
+	}
+	if a.id < b.id {
+		lockA()
+		b.m.RLock()
+	} else {
+		b.m.RLock()
+		lockA()
+	}
+	return func() {
+		b.m.RUnlock()
+		unlockA()
 	}
 }
 
@@ -163,7 +207,7 @@ func split(n *node, offset int) (*node, bool) {
 		return nil, false
 	}
 
-	newNode := &node{Prefix: n.Prefix[offset:]}
+	newNode := &node{Prefix: n.Prefix[offset:], count: n.count}
 	newNode.Children = n.Children
 	newNode.Leaf = n.Leaf
 	newNode.Idx = map[rune]*node{
@@ -172,6 +216,7 @@ func split(n *node, offset int) (*node, bool) {
 	n.Children = newNode
 	n.Leaf = nil
 	n.Prefix = n.Prefix[:offset]
+	n.fresh = false
 	return newNode, true
 }
 
@@ -181,14 +226,23 @@ func (T *RTree) Insert(key string, val interface{}) (interface{}, error) {
 	T.m.Lock()
 	defer T.m.Unlock()
 
+	oldVal, err := T.insert(key, val)
+	T.refreshKey(key)
+	return oldVal, err
+}
+
+// insert is same as Insert but it doesn't acquire the lock
+func (T *RTree) insert(key string, val interface{}) (interface{}, error) {
 	if len(key) == 0 {
 		return nil, ErrEmptyKey
 	}
+	T.version++
 	if T.root == nil {
 		T.root = &node{
 			Prefix: key,
 			Leaf:   &leafNode{Val: val},
 			Idx:    map[rune]*node{},
+			count:  1,
 		}
 		T.root.Idx[getRune1(key)] = T.root
 		T.size = 1
@@ -203,6 +257,7 @@ func (T *RTree) Insert(key string, val interface{}) (interface{}, error) {
 	var rune1 rune
 	var matchedNode *node
 	var node1 = T.root
+	path := []*node{} // matched nodes, their counts are increased if the key is added
 	for {
 		// search the key level by level
 		rune1 = []rune(pathSuffix)[0]
@@ -214,9 +269,14 @@ func (T *RTree) Insert(key string, val interface{}) (interface{}, error) {
 			node1.Next = newNode
 			node1.Idx[rune1] = newNode
 			T.size++
+			addCount(path, 1)
 			return nil, nil
 		}
 
+		// matchedNode or its descendants will be changed
+		matchedNode.fresh = false
+		path = append(path, matchedNode)
+
 		offset := commonPrefixOffset(matchedNode.Prefix, pathSuffix)
 		if offset == -1 {
 			// this is impossible
@@ -234,12 +294,14 @@ func (T *RTree) Insert(key string, val interface{}) (interface{}, error) {
 				childNode.Next = newNode(newNodePrefix, nil, nil, &leafNode{Val: val})
 				childNode.Idx[[]rune(newNodePrefix)[0]] = childNode.Next
 				T.size++
+				addCount(path, 1)
 				return nil, nil
 			}
 			// pathSuffix is same as n'prefix, update n's leaf
 			// matchedNode must have no leaf because it was just splitted
 			matchedNode.Leaf = &leafNode{Val: val}
 			T.size++
+			addCount(path, 1)
 			return nil, nil
 		}
 		if offset < len(pathSuffix)-1 {
@@ -255,10 +317,14 @@ func (T *RTree) Insert(key string, val interface{}) (interface{}, error) {
 			matchedNode.Children.Idx = map[rune]*node{}
 			matchedNode.Children.Idx[[]rune(newNodePrefix)[0]] = matchedNode.Children
 			T.size++
+			addCount(path, 1)
 			return nil, nil
 		}
 
 		// update current node's leaf
+		if matchedNode.Leaf == nil {
+			addCount(path, 1)
+		}
 		return T.updateLeafVal(matchedNode, key, val)
 	}
 }
@@ -279,17 +345,18 @@ func (T *RTree) updateLeafVal(n *node, key string, newVal interface{}) (interfac
 }
 
 // merge merges parent node and parent's first child node
+// parent's siblings are not affected because the first rune of parent is not changed
 func merge(parent *node, child *node) bool {
 	if parent != nil &&
 		parent.Children != nil &&
 		parent.Children == child &&
 		parent.Leaf == nil &&
-		parent.Next == nil &&
 		child.Next == nil {
 
 		parent.Prefix = parent.Prefix + child.Prefix
 		parent.Leaf = child.Leaf
 		parent.Children = child.Children
+		parent.fresh = false
 		return true
 	}
 	return false
@@ -302,9 +369,17 @@ func (T *RTree) Remove(key string) bool {
 	T.m.Lock()
 	defer T.m.Unlock()
 
+	removed := T.remove(key)
+	T.refreshKey(key)
+	return removed
+}
+
+// remove is same as Remove but it doesn't acquire the lock
+func (T *RTree) remove(key string) bool {
 	if len(key) == 0 {
 		return false
 	}
+	T.version++
 
 	// TODO: it is a little confuse here
 	// because at the root level, parent is actually a sibling of the child, not parent
@@ -314,6 +389,7 @@ func (T *RTree) Remove(key string) bool {
 	var matchedNode *node
 	var ok bool
 	var rune1 rune
+	path := []*node{} // matched nodes, their counts are decreased if the key is removed
 	for {
 		if node1 == nil {
 			return false
@@ -324,6 +400,9 @@ func (T *RTree) Remove(key string) bool {
 			// no match at this level
 			return false
 		}
+		// matchedNode or its descendants may be changed
+		matchedNode.fresh = false
+		path = append(path, matchedNode)
 
 		offset := commonPrefixOffset(matchedNode.Prefix, pathSuffix)
 		if offset == -1 {
@@ -337,6 +416,7 @@ func (T *RTree) Remove(key string) bool {
 		} else if offset == len(matchedNode.Prefix)-1 &&
 			offset == len(pathSuffix)-1 &&
 			matchedNode.Leaf != nil {
+			addCount(path, -1)
 			return T.removeChild(parent, matchedNode, parent == node1)
 		}
 		return false
@@ -367,6 +447,8 @@ func (T *RTree) removeChild(parent *node, child *node, isParentSameLevel bool) b
 			child.Next.Idx = child.Idx
 		}
 		parent.Children = child.Next
+		// parent may have only 1 child left
+		merge(parent, parent.Children)
 		return true
 	}
 	// child is not the first child
@@ -400,63 +482,383 @@ func (T *RTree) removeChild(parent *node, child *node, isParentSameLevel bool) b
 	return true
 }
 
-func getRune1(key string) rune {
-	return []rune(key)[0]
+// detach unlinks node n from the level under parent,
+// n is at the first level if parent is nil.
+// if n is the first node of the level, the index is moved to its next node
+func (T *RTree) detach(parent *node, n *node) {
+	first := T.root
+	if parent != nil {
+		first = parent.Children
+		parent.fresh = false
+	}
+
+	delete(first.Idx, getRune1(n.Prefix))
+	if first == n {
+		if n.Next != nil {
+			n.Next.Idx = n.Idx
+		}
+		if parent != nil {
+			parent.Children = n.Next
+		} else {
+			T.root = n.Next
+		}
+		n.Idx = nil
+		n.Next = nil
+		return
+	}
+
+	previous := first
+	for previous != nil && previous.Next != n {
+		previous = previous.Next
+	}
+	if previous == nil {
+		panic(fmt.Sprintf("the previous node not found parent(%+v) node(%+v)", parent, n))
+	}
+	previous.Next = n.Next
+	n.Next = nil
 }
 
-// GetAllPrefixMatches returns all prefix matches in the tree according to the key
-// if no match is found, it returns an empty map
-func (T *RTree) GetAllPrefixMatches(key string) map[string]interface{} {
-	T.m.RLock()
-	defer T.m.RUnlock()
+// attach adds node n into the level under parent,
+// n is added to the first level if parent is nil.
+// the first rune of n must not exist in the level
+func (T *RTree) attach(parent *node, n *node) {
+	first := T.root
+	if parent != nil {
+		first = parent.Children
+		parent.fresh = false
+	}
 
-	resultMap := map[string]interface{}{}
+	if first == nil {
+		n.Idx = map[rune]*node{getRune1(n.Prefix): n}
+		if parent != nil {
+			parent.Children = n
+		} else {
+			T.root = n
+		}
+		return
+	}
+	n.Next = first.Next
+	first.Next = n
+	first.Idx[getRune1(n.Prefix)] = n
+}
+
+// compact walks up along the path (from the first level to the deepest level)
+// it removes nodes which have neither leaf nor children
+// and merges nodes which have no leaf and only one child
+func (T *RTree) compact(path []*node) {
+	for i := len(path) - 1; i >= 0; i-- {
+		n := path[i]
+		if n.Leaf == nil && n.Children == nil {
+			var parent *node
+			if i > 0 {
+				parent = path[i-1]
+			}
+			T.detach(parent, n)
+			continue
+		}
+		merge(n, n.Children)
+		return
+	}
+}
+
+// countLeaves returns the number of values stored in the level starting from first and their descendants
+func countLeaves(first *node) int {
+	count := 0
+	for n := first; n != nil; n = n.Next {
+		count += n.count
+	}
+	return count
+}
+
+// addCount adds delta to counts of nodes in the path
+func addCount(path []*node, delta int) {
+	for _, n := range path {
+		n.count += delta
+	}
+}
+
+// RemovePrefix deletes all keys which start with the prefix in one operation
+// and it returns the number of removed keys.
+func (T *RTree) RemovePrefix(prefix string) int {
+	T.m.Lock()
+	defer T.m.Unlock()
+
+	removed := T.removePrefix(prefix)
+	T.refreshKey(prefix)
+	return removed
+}
+
+// removePrefix is same as RemovePrefix but it doesn't acquire the lock
+func (T *RTree) removePrefix(prefix string) int {
 	if T.root == nil {
-		return resultMap
-	} else if len(key) == 0 {
-		return resultMap
+		return 0
+	} else if len(prefix) == 0 {
+		return 0
 	}
+	T.version++
 
 	var ok bool
 	var rune1 rune
 	var matchedNode *node
+	path := []*node{} // matched nodes above matchedNode
 	node1 := T.root
-	pathSuffix := key
-	baseOffset := 0 // key[:baseOffset+1] is matched
+	pathSuffix := prefix
 	for {
 		if node1 == nil {
-			break
+			return 0
 		}
 
 		rune1 = getRune1(pathSuffix)
 		matchedNode, ok = node1.Idx[rune1]
 		if !ok {
-			break
+			return 0
 		}
 
 		offset := commonPrefixOffset(matchedNode.Prefix, pathSuffix)
 		if offset == -1 {
 			// this is impossible
-			panic(errImpossible(matchedNode.Prefix, key))
-		} else if offset < len(matchedNode.Prefix)-1 {
+			panic(errImpossible(matchedNode.Prefix, prefix))
+		} else if offset == len(pathSuffix)-1 {
+			// all keys in matchedNode start with the prefix
 			break
+		} else if offset == len(matchedNode.Prefix)-1 {
+			// descendants of matchedNode may be changed
+			matchedNode.fresh = false
+			path = append(path, matchedNode)
+			pathSuffix = pathSuffix[offset+1:]
+			node1 = matchedNode.Children
+			continue
 		}
-		if matchedNode.Leaf != nil {
-			resultMap[key[:baseOffset+offset+1]] = matchedNode.Leaf.Val
+		return 0
+	}
+
+	var parent *node
+	if len(path) > 0 {
+		parent = path[len(path)-1]
+	}
+	removed := matchedNode.count
+	addCount(path, -removed)
+	T.detach(parent, matchedNode)
+	T.size -= removed
+	T.compact(path)
+	return removed
+}
+
+// RemoveRange deletes all keys in the range [start, end) in lexicographic order
+// and it returns the number of removed keys.
+// subtrees which are entirely inside the range are removed without visiting their keys.
+func (T *RTree) RemoveRange(start, end string) int {
+	T.m.Lock()
+	defer T.m.Unlock()
+
+	if T.root == nil {
+		return 0
+	} else if start >= end {
+		return 0
+	}
+	T.version++
+
+	removed := T.removeRange(nil, "", start, end)
+	T.size -= removed
+	// nodes partially in the range are along the paths of start and end
+	T.refreshKey(start)
+	T.refreshKey(end)
+	return removed
+}
+
+// removeRange deletes keys in the range [start, end) from the level under parent
+// base is the key of the parent, and parent is nil at the first level
+func (T *RTree) removeRange(parent *node, base, start, end string) int {
+	first := T.root
+	if parent != nil {
+		first = parent.Children
+	}
+
+	removed := 0
+	for n := first; n != nil; {
+		next := n.Next // n.Next is reset if n is detached
+		// all keys under n start with key
+		key := base + n.Prefix
+		if key >= end || (key < start && !strings.HasPrefix(start, key)) {
+			// all keys under n are out of the range
+		} else if key >= start && !strings.HasPrefix(end, key) {
+			// all keys under n are in the range
+			removed += n.count
+			T.detach(parent, n)
+		} else {
+			// part of keys under n are in the range
+			n.fresh = false
+			partial := T.removeRange(n, key, start, end)
+			if n.Leaf != nil && key >= start {
+				n.Leaf = nil
+				partial++
+			}
+			n.count -= partial
+			removed += partial
+			if n.Leaf == nil && n.Children == nil {
+				T.detach(parent, n)
+			} else {
+				merge(n, n.Children)
+			}
 		}
+		n = next
+	}
+	return removed
+}
 
-		if offset == len(pathSuffix)-1 {
-			break
+func getRune1(key string) rune {
+	return []rune(key)[0]
+}
+
+// refresh recomputes summaries (hashes and aggregates) of nodes which are not fresh,
+// changed nodes and all their ancestors must be marked as not fresh before refreshing.
+// counts are not summaries here, they are updated by mutations along their paths.
+func (T *RTree) refresh() {
+	if T.hashValue == nil && T.aggregator == nil {
+		return
+	}
+	for n := T.root; n != nil; n = n.Next {
+		T.refreshNode(n)
+	}
+}
+
+// refreshKey refreshes nodes changed by a mutation on the key,
+// they are all under the first-level node with the first rune of the key,
+// so other first-level nodes are not visited
+func (T *RTree) refreshKey(key string) {
+	if len(key) == 0 || T.root == nil || (T.hashValue == nil && T.aggregator == nil) {
+		return
+	}
+	if n, ok := T.root.Idx[getRune1(key)]; ok {
+		T.refreshNode(n)
+	}
+}
+
+// refreshNode refreshes node n and its descendants which are not fresh,
+// only children lists of nodes which are not fresh are visited
+func (T *RTree) refreshNode(n *node) {
+	if n.fresh || (T.hashValue == nil && T.aggregator == nil) {
+		return
+	}
+	for child := n.Children; child != nil; child = child.Next {
+		if !child.fresh {
+			T.refreshNode(child)
 		}
-		pathSuffix = pathSuffix[offset+1:]
-		node1 = matchedNode.Children
-		baseOffset += offset + 1
-		continue
 	}
+	n.fresh = true
 
+	children := sortedLevel(n.Children)
+	if T.hashValue != nil {
+		var valueHash []byte
+		if n.Leaf != nil {
+			valueHash = T.hashValue(n.Leaf.Val)
+		}
+		hashes := make([][]byte, 0, len(children))
+		for _, child := range children {
+			hashes = append(hashes, child.hash)
+		}
+		n.hash = hashNode(n.Prefix, n.Leaf != nil, valueHash, hashLevel(hashes))
+	}
+	if T.aggregator != nil {
+		// values are combined by the order of keys
+		n.agg = T.aggregator.Identity()
+		if n.Leaf != nil {
+			n.agg = T.aggregator.FromValue(n.Leaf.Val)
+		}
+		for _, child := range children {
+			n.agg = T.aggregator.Combine(n.agg, child.agg)
+		}
+	}
+}
+
+// markAll marks all nodes as not fresh
+func (T *RTree) markAll() {
+	BFS(T, func(n Node) {
+		n.(*node).fresh = false
+	})
+}
+
+// sortedLevel returns nodes of the level which starts from first, and they are sorted by prefixes
+func sortedLevel(first *node) []*node {
+	nodes := []*node{}
+	for n := first; n != nil; n = n.Next {
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Prefix < nodes[j].Prefix })
+	return nodes
+}
+
+// walkSorted calls fn with keys and values under node n in lexicographic order,
+// base is the key of n's parent, and n's siblings are not visited
+func walkSorted(n *node, base string, fn func(key string, val interface{})) {
+	key := base + n.Prefix
+	if n.Leaf != nil {
+		fn(key, n.Leaf.Val)
+	}
+	for _, child := range sortedLevel(n.Children) {
+		walkSorted(child, key, fn)
+	}
+}
+
+// GetAllPrefixMatches returns all prefix matches in the tree according to the key
+// if no match is found, it returns an empty map
+func (T *RTree) GetAllPrefixMatches(key string) map[string]interface{} {
+	T.m.RLock()
+	defer T.m.RUnlock()
+
+	resultMap := map[string]interface{}{}
+	T.walkPath(key, func(prefix string, val interface{}) bool {
+		resultMap[prefix] = val
+		return true
+	})
 	return resultMap
 }
 
+// PrefixMatches returns all prefix matches of the key in the order of their lengths (from short to long)
+// if no match is found, it returns an empty slice
+func (T *RTree) PrefixMatches(key string) []KV {
+	T.m.RLock()
+	defer T.m.RUnlock()
+
+	matches := []KV{}
+	T.walkPath(key, func(prefix string, val interface{}) bool {
+		matches = append(matches, KV{Key: prefix, Val: val})
+		return true
+	})
+	return matches
+}
+
+// WalkPath calls fn with all prefix matches of the key in the order of their lengths (from short to long)
+// it stops if fn returns false.
+// fn must not modify the tree because the tree is locked during walking.
+func (T *RTree) WalkPath(key string, fn func(prefix string, val interface{}) bool) {
+	T.m.RLock()
+	defer T.m.RUnlock()
+
+	T.walkPath(key, fn)
+}
+
+// walkPath is same as WalkPath but it doesn't acquire the lock
+func (T *RTree) walkPath(key string, fn func(prefix string, val interface{}) bool) {
+	children := T.root
+	pathSuffix := key
+	matched := 0 // key[:matched] is matched
+	for children != nil && len(pathSuffix) > 0 {
+		rune1, _ := utf8.DecodeRuneInString(pathSuffix)
+		matchedNode, ok := children.Idx[rune1]
+		if !ok || !strings.HasPrefix(pathSuffix, matchedNode.Prefix) {
+			return
+		}
+
+		matched += len(matchedNode.Prefix)
+		if matchedNode.Leaf != nil && !fn(key[:matched], matchedNode.Leaf.Val) {
+			return
+		}
+		pathSuffix = pathSuffix[len(matchedNode.Prefix):]
+		children = matchedNode.Children
+	}
+}
+
 type traverseLog struct {
 	n    *node
 	base string
@@ -552,17 +954,13 @@ func (T *RTree) GetBestMatch(key string) (string, interface{}, bool) {
 	T.m.RLock()
 	defer T.m.RUnlock()
 
-	matches := T.GetAllPrefixMatches(key)
-	if len(matches) == 0 {
-		return "", nil, false
-	}
-	bestPrefix := ""
-	for prefix := range matches {
-		if len(prefix) > len(bestPrefix) {
-			bestPrefix = prefix
-		}
-	}
-	return bestPrefix, matches[bestPrefix], true
+	var bestVal interface{}
+	bestPrefix, found := "", false
+	T.walkPath(key, func(prefix string, val interface{}) bool {
+		bestPrefix, bestVal, found = prefix, val, true
+		return true
+	})
+	return bestPrefix, bestVal, found
 }
 
 type visitLog struct {
@@ -31,6 +31,8 @@ func TestWithRandomKeys(t *testing.T) {
 	for i := 0; i < *testRound; i++ {
 		randomTest(t)
 		longerKeyTest(t)
+		removePrefixTest(t)
+		removeRangeTest(t)
 	}
 }
 
@@ -77,6 +79,14 @@ func randomTest(t *testing.T) {
 			printMap(dict)
 			t.Fatalf("incorrect prefix matches")
 		}
+		orderedPrefixes := tree.PrefixMatches(key)
+		if !checkOrderedPrefixMatches(orderedPrefixes, prefixes) {
+			fmt.Printf("ordered prefixes of (%s): %+v\n", key, orderedPrefixes)
+			printActions(actions)
+			printRTree(tree)
+			printMap(dict)
+			t.Fatalf("incorrect ordered prefix matches")
+		}
 
 		// test marshaling
 		tree2 := NewRTree()
@@ -145,6 +155,70 @@ func longerKeyTest(t *testing.T) {
 	}
 }
 
+func removePrefixTest(t *testing.T) {
+	var actions []string
+	tree := NewRTree()
+	dict := make(map[string]string)
+	randomStrings := GetTestStrings()
+
+	for i := 0; i < *actionCount; i++ {
+		key := randomStrings[rand.Intn(len(randomStrings))]
+		doRandomAction(&actions, key, tree, dict)
+	}
+
+	randomKey := randomStrings[rand.Intn(len(randomStrings))]
+	randomKey = randomKey[:rand.Intn(len(randomKey))+1]
+	removed := tree.RemovePrefix(randomKey)
+	expected := 0
+	for key := range dict {
+		if strings.HasPrefix(key, randomKey) {
+			delete(dict, key)
+			expected++
+		}
+	}
+	if removed != expected || tree.Size() != len(dict) || !isEqual(tree, dict) {
+		fmt.Printf("RemovePrefix(%s): removed(%d) expected(%d)\n", randomKey, removed, expected)
+		printActions(actions)
+		printRTree(tree)
+		printMap(dict)
+		t.Fatalf("incorrect prefix removal, seed: %d", *seed)
+	}
+}
+
+func removeRangeTest(t *testing.T) {
+	var actions []string
+	tree := NewRTree()
+	dict := make(map[string]string)
+	randomStrings := GetTestStrings()
+
+	for i := 0; i < *actionCount; i++ {
+		key := randomStrings[rand.Intn(len(randomStrings))]
+		doRandomAction(&actions, key, tree, dict)
+	}
+
+	start := randomStrings[rand.Intn(len(randomStrings))]
+	end := randomStrings[rand.Intn(len(randomStrings))]
+	start = start[:rand.Intn(len(start))+1]
+	if start > end {
+		start, end = end, start
+	}
+	removed := tree.RemoveRange(start, end)
+	expected := 0
+	for key := range dict {
+		if key >= start && key < end {
+			delete(dict, key)
+			expected++
+		}
+	}
+	if removed != expected || tree.Size() != len(dict) || !isEqual(tree, dict) {
+		fmt.Printf("RemoveRange(%s, %s): removed(%d) expected(%d)\n", start, end, removed, expected)
+		printActions(actions)
+		printRTree(tree)
+		printMap(dict)
+		t.Fatalf("incorrect range removal, seed: %d", *seed)
+	}
+}
+
 func checkPrefixMatches(
 	key string,
 	prefixes map[string]interface{},
@@ -165,6 +239,22 @@ func checkPrefixMatches(
 	return true
 }
 
+// checkOrderedPrefixMatches checks if ordered matches are same as matches and they are sorted by lengths
+func checkOrderedPrefixMatches(orderedMatches []KV, matches map[string]interface{}) bool {
+	if len(orderedMatches) != len(matches) {
+		return false
+	}
+	for i, match := range orderedMatches {
+		if _, ok := matches[match.Key]; !ok {
+			return false
+		}
+		if i > 0 && len(orderedMatches[i-1].Key) >= len(match.Key) {
+			return false
+		}
+	}
+	return true
+}
+
 // checkLongerMatches only checks if key is the prefix of each longerMatches
 // Becasue currently, besides the first one,
 // it doesn't return results by the order of how much it is closed to the key
@@ -257,7 +347,22 @@ func isEqual(tree *RTree, dict map[string]string) bool {
 	}
 
 	// check if all keys in rtree are also in map
-	return preOrderAndCompare(tree.root, dict)
+	return preOrderAndCompare(tree.root, dict) && checkCounts(tree.root)
+}
+
+// checkCounts checks that counts of nodes in the level and their descendants are the numbers of their values
+func checkCounts(first *node) bool {
+	for n := first; n != nil; n = n.Next {
+		expected := countLeaves(n.Children)
+		if n.Leaf != nil {
+			expected++
+		}
+		if n.count != expected || !checkCounts(n.Children) {
+			fmt.Printf("count of node(%s) is %d but %d expected\n", n.Prefix, n.count, expected)
+			return false
+		}
+	}
+	return true
 }
 
 func preOrderAndCompare(n *node, M map[string]string) bool {
@@ -6,9 +6,13 @@ import (
 
 func TestOperations(t *testing.T) {
 	t.Run("test Insert", testInsert)
+	t.Run("test Root", testRoot)
 	t.Run("test Remove", testRemove)
 	t.Run("test GetAllMatches", testGetAllMatches)
 	t.Run("test GetBestMatch", testGetBestMatch)
+	t.Run("test RemovePrefix", testRemovePrefix)
+	t.Run("test RemoveRange", testRemoveRange)
+	t.Run("test PrefixMatches", testPrefixMatches)
 }
 
 func testInsert(t *testing.T) {
@@ -32,6 +36,20 @@ func testInsert(t *testing.T) {
 	}
 }
 
+func testRoot(t *testing.T) {
+	rTree := NewRTree()
+	if _, ok := rTree.Root(); ok {
+		t.Error("an empty tree should have no root")
+	}
+
+	for _, key := range []string{"a", "b", "ab"} {
+		rTree.Insert(key, key)
+	}
+	if root, ok := rTree.Root(); !ok || root.Segment() != "a" {
+		t.Error("the root of radix tree is not correct")
+	}
+}
+
 func testRemove(t *testing.T) {
 	rTree := NewRTree()
 	values := []string{
@@ -169,3 +187,210 @@ func testGetBestMatch(t *testing.T) {
 		}
 	}
 }
+
+func testRemovePrefix(t *testing.T) {
+	type TestCase struct {
+		desc    string
+		inserts []string
+		prefix  string
+		removed []string
+	}
+
+	testCases := []*TestCase{
+		&TestCase{
+			desc:    "prefix ends at the end of a node",
+			inserts: []string{"a", "ab", "abc", "abd", "b"},
+			prefix:  "ab",
+			removed: []string{"ab", "abc", "abd"},
+		},
+		&TestCase{
+			desc:    "prefix ends in the middle of a node",
+			inserts: []string{"a", "abcd", "abce", "b"},
+			prefix:  "ab",
+			removed: []string{"abcd", "abce"},
+		},
+		&TestCase{
+			desc:    "remove the whole first level",
+			inserts: []string{"a", "ab", "b", "ba"},
+			prefix:  "a",
+			removed: []string{"a", "ab"},
+		},
+		&TestCase{
+			desc:    "remove the parent's first child and merge the parent",
+			inserts: []string{"abc", "abd", "abe", "x"},
+			prefix:  "abc",
+			removed: []string{"abc"},
+		},
+		&TestCase{
+			desc:    "multi-bytes runes",
+			inserts: []string{"中", "中文", "中国", "世界"},
+			prefix:  "中",
+			removed: []string{"中", "中文", "中国"},
+		},
+		&TestCase{
+			desc:    "no key starts with the prefix",
+			inserts: []string{"a", "ab"},
+			prefix:  "abc",
+			removed: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		rTree := NewRTree()
+		for _, insert := range tc.inserts {
+			rTree.Insert(insert, insert)
+		}
+
+		removed := rTree.RemovePrefix(tc.prefix)
+		if removed != len(tc.removed) {
+			t.Errorf("RemovePrefix(%s): got %d expect %d", tc.desc, removed, len(tc.removed))
+		}
+		if rTree.Size() != len(tc.inserts)-len(tc.removed) {
+			t.Errorf("RemovePrefix(%s): the size of radix tree is not correct", tc.desc)
+		}
+		removedSet := map[string]bool{}
+		for _, key := range tc.removed {
+			removedSet[key] = true
+		}
+		for _, key := range tc.inserts {
+			_, err := rTree.Get(key)
+			if removedSet[key] && err == nil {
+				t.Errorf("RemovePrefix(%s): %s should be removed", tc.desc, key)
+			} else if !removedSet[key] && err != nil {
+				t.Errorf("RemovePrefix(%s): %s should not be removed: %s", tc.desc, key, err)
+			}
+		}
+	}
+}
+
+func testRemoveRange(t *testing.T) {
+	type TestCase struct {
+		desc    string
+		inserts []string
+		start   string
+		end     string
+		removed []string
+	}
+
+	testCases := []*TestCase{
+		&TestCase{
+			desc:    "end is excluded",
+			inserts: []string{"a", "ab", "b", "ba", "c"},
+			start:   "ab",
+			end:     "ba",
+			removed: []string{"ab", "b"},
+		},
+		&TestCase{
+			desc:    "bounds are not stored keys",
+			inserts: []string{"events/2026-01-01", "events/2026-01-02", "events/2026-02-01", "events/2026-03-01"},
+			start:   "events/",
+			end:     "events/2026-02-15",
+			removed: []string{"events/2026-01-01", "events/2026-01-02", "events/2026-02-01"},
+		},
+		&TestCase{
+			desc:    "range covers the whole tree",
+			inserts: []string{"a", "ab", "b", "中文"},
+			start:   "",
+			end:     "中文字",
+			removed: []string{"a", "ab", "b", "中文"},
+		},
+		&TestCase{
+			desc:    "range is inside a node",
+			inserts: []string{"abcd", "abce", "x"},
+			start:   "abc",
+			end:     "abcd",
+			removed: []string{},
+		},
+		&TestCase{
+			desc:    "empty range",
+			inserts: []string{"a", "b"},
+			start:   "b",
+			end:     "a",
+			removed: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		rTree := NewRTree()
+		for _, insert := range tc.inserts {
+			rTree.Insert(insert, insert)
+		}
+
+		removed := rTree.RemoveRange(tc.start, tc.end)
+		if removed != len(tc.removed) {
+			t.Errorf("RemoveRange(%s): got %d expect %d", tc.desc, removed, len(tc.removed))
+		}
+		if rTree.Size() != len(tc.inserts)-len(tc.removed) {
+			t.Errorf("RemoveRange(%s): the size of radix tree is not correct", tc.desc)
+		}
+		removedSet := map[string]bool{}
+		for _, key := range tc.removed {
+			removedSet[key] = true
+		}
+		for _, key := range tc.inserts {
+			_, err := rTree.Get(key)
+			if removedSet[key] && err == nil {
+				t.Errorf("RemoveRange(%s): %s should be removed", tc.desc, key)
+			} else if !removedSet[key] && err != nil {
+				t.Errorf("RemoveRange(%s): %s should not be removed: %s", tc.desc, key, err)
+			}
+		}
+	}
+}
+
+func testPrefixMatches(t *testing.T) {
+	type TestCase struct {
+		desc    string
+		inserts []string
+		get     string
+		expect  []string
+	}
+
+	testCases := []*TestCase{
+		&TestCase{
+			desc:    "match along 1 of 2 braches",
+			inserts: []string{"abcd", "ab", "a", "ac", "abc"},
+			get:     "abc",
+			expect:  []string{"a", "ab", "abc"},
+		},
+		&TestCase{
+			desc:    "found matches shorter than key",
+			inserts: []string{"中", "中文", "中文字典"},
+			get:     "中文字",
+			expect:  []string{"中", "中文"},
+		},
+		&TestCase{
+			desc:    "no match found",
+			inserts: []string{"ab", "abd"},
+			get:     "a",
+			expect:  []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		rTree := NewRTree()
+		for _, insert := range tc.inserts {
+			rTree.Insert(insert, insert)
+		}
+
+		matches := rTree.PrefixMatches(tc.get)
+		if len(matches) != len(tc.expect) {
+			t.Errorf("PrefixMatches(%s): got %+v expect %+v", tc.desc, matches, tc.expect)
+			continue
+		}
+		for i, match := range matches {
+			if match.Key != tc.expect[i] || match.Val.(string) != tc.expect[i] {
+				t.Errorf("PrefixMatches(%s): got %+v expect %s", tc.desc, match, tc.expect[i])
+			}
+		}
+
+		walked := []string{}
+		rTree.WalkPath(tc.get, func(prefix string, val interface{}) bool {
+			walked = append(walked, prefix)
+			return len(walked) < 2
+		})
+		if len(tc.expect) > 2 && len(walked) != 2 {
+			t.Errorf("WalkPath(%s): it should stop after 2 matches %+v", tc.desc, walked)
+		}
+	}
+}
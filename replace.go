@@ -0,0 +1,132 @@
+package qradix
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// replaceBufferSize is the size of the lookahead buffer of ReplaceReader,
+// keys longer than it are not matched in streams.
+const replaceBufferSize = 64 << 10
+
+// span is a match of a key in a text
+type span struct {
+	start, end int
+	val        interface{}
+}
+
+// ReplaceAll returns a copy of the text in which leftmost-longest matches of keys are replaced
+// with the results of replacer, the text is scanned in a single pass and replaced texts are not scanned again.
+// The tree is not locked while replacer is called, so replacer can access the tree.
+func (T *RTree) ReplaceAll(text string, replacer func(key string, val interface{}) string) string {
+	spans := []span{}
+	T.m.RLock()
+	for start := 0; start < len(text); {
+		matched, val, ok := T.matchLongest(func(offset int) (rune, int, bool) {
+			if start+offset >= len(text) {
+				return 0, 0, false
+			}
+			r, size := utf8.DecodeRuneInString(text[start+offset:])
+			return r, size, true
+		})
+		if ok {
+			spans = append(spans, span{start: start, end: start + matched, val: val})
+			start += matched
+		} else {
+			_, size := utf8.DecodeRuneInString(text[start:])
+			start += size
+		}
+	}
+	T.m.RUnlock()
+
+	var builder strings.Builder
+	copied := 0
+	for _, match := range spans {
+		builder.WriteString(text[copied:match.start])
+		builder.WriteString(replacer(text[match.start:match.end], match.val))
+		copied = match.end
+	}
+	builder.WriteString(text[copied:])
+	return builder.String()
+}
+
+// ReplaceReader works like ReplaceAll but reads the text from src and writes the result to dst,
+// matches are looked ahead in a buffer so keys longer than 64KB are not matched.
+// The tree is read locked while a match is being looked for, and not locked while replacer is called.
+func (T *RTree) ReplaceReader(dst io.Writer, src io.Reader, replacer func(key string, val interface{}) string) error {
+	reader := bufio.NewReaderSize(src, replaceBufferSize)
+	writer := bufio.NewWriter(dst)
+
+	for {
+		var readErr error
+		T.m.RLock()
+		matched, val, ok := T.matchLongest(peekRunes(reader, &readErr))
+		T.m.RUnlock()
+		if readErr != nil {
+			return readErr
+		}
+
+		if ok {
+			buf, _ := reader.Peek(matched)
+			if _, err := writer.WriteString(replacer(string(buf), val)); err != nil {
+				return err
+			}
+			reader.Discard(matched)
+			continue
+		}
+
+		buf, err := reader.Peek(utf8.UTFMax)
+		if err != nil && err != io.EOF {
+			return err
+		} else if len(buf) == 0 {
+			return writer.Flush()
+		}
+		_, size := utf8.DecodeRune(buf)
+		if _, err = writer.Write(buf[:size]); err != nil {
+			return err
+		}
+		reader.Discard(size)
+	}
+}
+
+// matchLongest walks from the root with runes returned by next(offset) until it returns false,
+// and returns the length of the longest key matched in bytes and its value.
+func (T *RTree) matchLongest(next func(offset int) (rune, int, bool)) (int, interface{}, bool) {
+	c := newCursor(T)
+	offset, matched := 0, 0
+	var matchedVal interface{}
+	for {
+		r, size, ok := next(offset)
+		if !ok || !c.step(r) {
+			break
+		}
+		offset += size
+		if val, ok := c.value(); ok {
+			matched, matchedVal = offset, val
+		}
+	}
+	return matched, matchedVal, matched > 0
+}
+
+// peeker is a reader which can look ahead without consuming, e.g. bufio.Reader
+type peeker interface {
+	Peek(n int) ([]byte, error)
+}
+
+// peekRunes returns a function for matchLongest which peeks runes from the reader,
+// it stops at the end of the input or the lookahead buffer, and errors are saved in readErr.
+func peekRunes(reader peeker, readErr *error) func(offset int) (rune, int, bool) {
+	return func(offset int) (rune, int, bool) {
+		buf, err := reader.Peek(offset + utf8.UTFMax)
+		if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			*readErr = err
+			return 0, 0, false
+		} else if len(buf) <= offset {
+			return 0, 0, false
+		}
+		r, size := utf8.DecodeRune(buf[offset:])
+		return r, size, true
+	}
+}
@@ -0,0 +1,128 @@
+package qradix
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReplace(t *testing.T) {
+	t.Run("test ReplaceAll and ReplaceReader", testReplace)
+	t.Run("test ReplaceAll with random keys", testReplaceWithRandomKeys)
+}
+
+func upperReplacer(key string, val interface{}) string {
+	return val.(string)
+}
+
+func testReplace(t *testing.T) {
+	tree := NewRTree()
+	for key, val := range map[string]string{
+		"a":    "[A]",
+		"ab":   "[AB]",
+		"abc":  "[ABC]",
+		"bcd":  "[BCD]",
+		"世界":   "[WORLD]",
+		"世界你好": "[HELLO WORLD]",
+	} {
+		tree.Insert(key, val)
+	}
+
+	cases := map[string]string{
+		"":           "",
+		"xyz":        "xyz",
+		"abcd":       "[ABC]d",
+		"abd":        "[AB]d",
+		"aabcd":      "[A][ABC]d",
+		"xbcdab":     "x[BCD][AB]",
+		"世界你好世界你":    "[HELLO WORLD][WORLD]你",
+		"a\xffabc":   "[A]\xff[ABC]",
+		"ab世界abc世界x": "[AB][WORLD][ABC][WORLD]x",
+	}
+	for text, expected := range cases {
+		if got := tree.ReplaceAll(text, upperReplacer); got != expected {
+			t.Errorf("ReplaceAll(%s): got %s expect %s", text, got, expected)
+		}
+
+		buf := &bytes.Buffer{}
+		err := tree.ReplaceReader(buf, iotest.OneByteReader(strings.NewReader(text)), upperReplacer)
+		if err != nil || buf.String() != expected {
+			t.Errorf("ReplaceReader(%s): got %s %v expect %s", text, buf.String(), err, expected)
+		}
+	}
+
+	// replacer can access the tree
+	got := tree.ReplaceAll("abc", func(key string, val interface{}) string {
+		tree.Insert(key+key, val)
+		return key
+	})
+	if got != "abc" || tree.Size() != 7 {
+		t.Errorf("ReplaceAll: got %s and size %d", got, tree.Size())
+	}
+
+	errRead := errors.New("read error")
+	err := tree.ReplaceReader(&bytes.Buffer{}, iotest.TimeoutReader(strings.NewReader(strings.Repeat("x", 5000))), upperReplacer)
+	if err == nil {
+		t.Errorf("ReplaceReader: error should be returned")
+	}
+	err = tree.ReplaceReader(&bytes.Buffer{}, iotest.DataErrReader(&errReader{err: errRead}), upperReplacer)
+	if err != errRead {
+		t.Errorf("ReplaceReader: got %v expect %v", err, errRead)
+	}
+}
+
+type errReader struct {
+	err error
+}
+
+func (r *errReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+func testReplaceWithRandomKeys(t *testing.T) {
+	seedRand()
+	for i := 0; i < *testRound; i++ {
+		var actions []string
+		tree := NewRTree()
+		dict := make(map[string]string)
+		randomStrings := GetTestStrings()
+
+		for j := 0; j < *actionCount; j++ {
+			doRandomAction(&actions, randomStrings[rand.Intn(len(randomStrings))], tree, dict)
+		}
+
+		text := ""
+		for j := 0; j < 10; j++ {
+			text += randomStrings[rand.Intn(len(randomStrings))]
+		}
+
+		// replace matches found by GetBestMatch at each offset
+		expected := ""
+		for offset := 0; offset < len(text); {
+			if key, _, ok := tree.GetBestMatch(text[offset:]); ok {
+				expected += "<" + key + ">"
+				offset += len(key)
+			} else {
+				size := len(string([]rune(text[offset:])[0]))
+				expected += text[offset : offset+size]
+				offset += size
+			}
+		}
+
+		replacer := func(key string, val interface{}) string {
+			return "<" + key + ">"
+		}
+		if got := tree.ReplaceAll(text, replacer); got != expected {
+			printActions(actions)
+			printRTree(tree)
+			t.Fatalf("ReplaceAll(%s): got %s expect %s, seed: %d", text, got, expected, *seed)
+		}
+		buf := &bytes.Buffer{}
+		if err := tree.ReplaceReader(buf, strings.NewReader(text), replacer); err != nil || buf.String() != expected {
+			t.Fatalf("ReplaceReader(%s): got %s %v expect %s, seed: %d", text, buf.String(), err, expected, *seed)
+		}
+	}
+}
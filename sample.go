@@ -0,0 +1,69 @@
+package qradix
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// RandomKey returns a stored key and its value chosen uniformly at random,
+// the global source of math/rand is used if rng is nil.
+// It returns false if the tree is empty.
+func (T *RTree) RandomKey(rng *rand.Rand) (string, interface{}, bool) {
+	T.m.RLock()
+	defer T.m.RUnlock()
+
+	if T.size == 0 {
+		return "", nil, false
+	}
+	key, val, err := T.selectKey(intn(rng, T.size))
+	return key, val, err == nil
+}
+
+// SamplePrefix returns at most n distinct keys, which start with the prefix, chosen uniformly at random,
+// so all keys with the prefix are returned if there are no more than n.
+// Results are sorted by keys and keys are not materialized except the chosen ones.
+// The global source of math/rand is used if rng is nil.
+func (T *RTree) SamplePrefix(prefix string, n int, rng *rand.Rand) []KV {
+	T.m.RLock()
+	defer T.m.RUnlock()
+
+	samples := []KV{}
+	// keys starting with the prefix are in the range [start, start+count) in lexicographic order
+	count := T.countPrefix(prefix)
+	start := T.rank(prefix)
+	if n <= 0 || count == 0 {
+		return samples
+	} else if n > count {
+		n = count
+	}
+
+	// choose n distinct offsets by Floyd's algorithm
+	chosen := map[int]bool{}
+	offsets := make([]int, 0, n)
+	for i := count - n; i < count; i++ {
+		offset := intn(rng, i+1)
+		if chosen[offset] {
+			offset = i
+		}
+		chosen[offset] = true
+		offsets = append(offsets, offset)
+	}
+	sort.Ints(offsets)
+
+	for _, offset := range offsets {
+		key, val, err := T.selectKey(start + offset)
+		if err != nil {
+			// this is impossible if counts are correct
+			panic(err)
+		}
+		samples = append(samples, KV{Key: key, Val: val})
+	}
+	return samples
+}
+
+func intn(rng *rand.Rand, n int) int {
+	if rng == nil {
+		return rand.Intn(n)
+	}
+	return rng.Intn(n)
+}
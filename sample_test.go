@@ -0,0 +1,74 @@
+package qradix
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestSample(t *testing.T) {
+	t.Run("test RandomKey", testRandomKey)
+	t.Run("test SamplePrefix", testSamplePrefix)
+}
+
+func testRandomKey(t *testing.T) {
+	tree := NewRTree()
+	rng := rand.New(rand.NewSource(1))
+	if _, _, ok := tree.RandomKey(rng); ok {
+		t.Fatal("RandomKey: empty tree should have no key")
+	}
+
+	keys := []string{"a", "ab", "abc", "abd", "b", "中文"}
+	for _, key := range keys {
+		tree.Insert(key, key)
+	}
+	hits := map[string]int{}
+	rounds := 6000
+	for i := 0; i < rounds; i++ {
+		key, val, ok := tree.RandomKey(rng)
+		if !ok || key != val.(string) {
+			t.Fatalf("RandomKey: got %s %v %t", key, val, ok)
+		}
+		hits[key]++
+	}
+	// each key is expected to be hit 1000 times
+	for _, key := range keys {
+		if hits[key] < 800 || hits[key] > 1200 {
+			t.Errorf("RandomKey: %s is hit %d times in %d rounds", key, hits[key], rounds)
+		}
+	}
+}
+
+func testSamplePrefix(t *testing.T) {
+	tree := NewRTree()
+	rng := rand.New(rand.NewSource(1))
+	keys := []string{"a", "ab", "abc", "abd", "abe", "b", "ba", "中文"}
+	for _, key := range keys {
+		tree.Insert(key, key)
+	}
+
+	for i := 0; i < 100; i++ {
+		samples := tree.SamplePrefix("ab", 2, rng)
+		if len(samples) != 2 {
+			t.Fatalf("SamplePrefix: got %+v", samples)
+		}
+		if samples[0].Key >= samples[1].Key {
+			t.Fatalf("SamplePrefix: samples should be distinct and sorted %+v", samples)
+		}
+		for _, sample := range samples {
+			if !strings.HasPrefix(sample.Key, "ab") || sample.Val.(string) != sample.Key {
+				t.Fatalf("SamplePrefix: invalid sample %+v", sample)
+			}
+		}
+	}
+
+	if samples := tree.SamplePrefix("a", 10, rng); len(samples) != 5 {
+		t.Errorf("SamplePrefix: all 5 keys should be returned %+v", samples)
+	}
+	if samples := tree.SamplePrefix("x", 10, rng); len(samples) != 0 {
+		t.Errorf("SamplePrefix: no key should be returned %+v", samples)
+	}
+	if samples := tree.SamplePrefix("", 0, rng); len(samples) != 0 {
+		t.Errorf("SamplePrefix: no key should be returned %+v", samples)
+	}
+}
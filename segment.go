@@ -0,0 +1,140 @@
+package qradix
+
+import (
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// Segmenter splits unspaced text (e.g. Chinese or Japanese) into words of a dictionary.
+// Numeric values in the dictionary are word frequencies, and other values are taken as frequency 1.
+// It is built from a snapshot of the dictionary, so it must be rebuilt after the dictionary is modified.
+// Segmenter is safe for concurrent use.
+type Segmenter struct {
+	matcher  *Matcher
+	logTotal float64
+}
+
+// edge is a word from its start to end in the DAG of a text
+type edge struct {
+	end    int
+	weight float64
+}
+
+// NewSegmenter returns a Segmenter using the tree as the dictionary
+func NewSegmenter(dict *RTree) *Segmenter {
+	matcher := NewMatcher(dict)
+	total := 0.0
+	for _, kv := range matcher.keys {
+		total += frequency(kv.Val)
+	}
+	if total < 1 {
+		total = 1
+	}
+	return &Segmenter{matcher: matcher, logTotal: math.Log(total)}
+}
+
+// frequency returns the word frequency of the value
+func frequency(val interface{}) float64 {
+	freq := 1.0
+	switch v := val.(type) {
+	case int:
+		freq = float64(v)
+	case int32:
+		freq = float64(v)
+	case int64:
+		freq = float64(v)
+	case uint:
+		freq = float64(v)
+	case uint32:
+		freq = float64(v)
+	case uint64:
+		freq = float64(v)
+	case float32:
+		freq = float64(v)
+	case float64:
+		freq = v
+	}
+	if freq <= 0 || math.IsNaN(freq) {
+		return 1
+	}
+	return freq
+}
+
+// dag returns words starting at every offset of the text, longer words come first
+func (s *Segmenter) dag(text string) [][]edge {
+	edges := make([][]edge, len(text)+1)
+	s.matcher.Scan(strings.NewReader(text), func(match Match) bool {
+		edges[match.Start] = append(edges[match.Start], edge{
+			end:    match.End,
+			weight: math.Log(frequency(match.Val)) - s.logTotal,
+		})
+		return true
+	})
+	for _, words := range edges {
+		// insertion sort, there are only a few words at an offset
+		for i := 1; i < len(words); i++ {
+			for j := i; j > 0 && words[j].end > words[j-1].end; j-- {
+				words[j], words[j-1] = words[j-1], words[j]
+			}
+		}
+	}
+	return edges
+}
+
+// MaxMatch segments the text by forward maximum matching:
+// the longest word starting at the current offset is taken,
+// and a rune which starts no word is taken as a word.
+func (s *Segmenter) MaxMatch(text string) []string {
+	edges := s.dag(text)
+	words := []string{}
+	for start := 0; start < len(text); {
+		end := start
+		if len(edges[start]) > 0 {
+			end = edges[start][0].end
+		} else {
+			_, size := utf8.DecodeRuneInString(text[start:])
+			end += size
+		}
+		words = append(words, text[start:end])
+		start = end
+	}
+	return words
+}
+
+// Viterbi segments the text by finding the most probable path in the DAG of all words in the text,
+// the probability of a word is its frequency divided by the total frequency of the dictionary,
+// and a rune which is not a word is taken as a word with frequency 1.
+func (s *Segmenter) Viterbi(text string) []string {
+	edges := s.dag(text)
+	best := make([]float64, len(text)+1)
+	next := make([]int, len(text)+1)
+
+	offsets := []int{}
+	for offset := range text {
+		offsets = append(offsets, offset)
+	}
+	for i := len(offsets) - 1; i >= 0; i-- {
+		start := offsets[i]
+		_, size := utf8.DecodeRuneInString(text[start:])
+		best[start], next[start] = math.Inf(-1), start+size
+		isWord := false
+		for _, word := range edges[start] {
+			if word.end == start+size {
+				isWord = true
+			}
+			if score := word.weight + best[word.end]; score > best[start] {
+				best[start], next[start] = score, word.end
+			}
+		}
+		if score := -s.logTotal + best[start+size]; !isWord && score > best[start] {
+			best[start], next[start] = score, start+size
+		}
+	}
+
+	words := []string{}
+	for start := 0; start < len(text); start = next[start] {
+		words = append(words, text[start:next[start]])
+	}
+	return words
+}
@@ -0,0 +1,90 @@
+package qradix
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestSegmenter(t *testing.T) {
+	t.Run("test MaxMatch and Viterbi", testSegmenter)
+	t.Run("test Segmenter with random keys", testSegmenterWithRandomKeys)
+}
+
+func testSegmenter(t *testing.T) {
+	dict := NewRTree()
+	freqs := map[string]int{"研究": 10, "研究生": 5, "生命": 10, "命": 2, "起源": 10, "\uFFFD": 1}
+	for word, freq := range freqs {
+		dict.Insert(word, freq)
+	}
+	segmenter := NewSegmenter(dict)
+
+	cases := []struct {
+		text     string
+		maxMatch string
+		viterbi  string
+	}{
+		{"研究生命起源", "研究生/命/起源", "研究/生命/起源"},
+		{"我研究生命", "我/研究生/命", "我/研究/生命"},
+		{"起源ab", "起源/a/b", "起源/a/b"},
+		// invalid bytes are matched as utf8.RuneError
+		{"\xff研究\xfe起源", "\xff/研究/\xfe/起源", "\xff/研究/\xfe/起源"},
+		{"", "", ""},
+	}
+	for _, tc := range cases {
+		if got := strings.Join(segmenter.MaxMatch(tc.text), "/"); got != tc.maxMatch {
+			t.Errorf("MaxMatch(%s): got %s expect %s", tc.text, got, tc.maxMatch)
+		}
+		if got := strings.Join(segmenter.Viterbi(tc.text), "/"); got != tc.viterbi {
+			t.Errorf("Viterbi(%s): got %s expect %s", tc.text, got, tc.viterbi)
+		}
+	}
+
+	// words without frequencies are equally likely, so fewer words are better
+	dict = NewRTree()
+	for _, word := range []string{"研究", "研究生", "生命", "命", "起源"} {
+		dict.Insert(word, word)
+	}
+	if got := strings.Join(NewSegmenter(dict).Viterbi("研究生命起源"), "/"); got != "研究/生命/起源" &&
+		got != "研究生/命/起源" {
+		t.Errorf("Viterbi: got %s", got)
+	}
+}
+
+func testSegmenterWithRandomKeys(t *testing.T) {
+	seedRand()
+	for i := 0; i < *testRound; i++ {
+		var actions []string
+		tree := NewRTree()
+		dict := make(map[string]string)
+		randomStrings := GetTestStrings()
+
+		for j := 0; j < *actionCount; j++ {
+			doRandomAction(&actions, randomStrings[rand.Intn(len(randomStrings))], tree, dict)
+		}
+		segmenter := NewSegmenter(tree)
+
+		text := ""
+		for j := 0; j < 10; j++ {
+			text += randomStrings[rand.Intn(len(randomStrings))]
+		}
+
+		maxMatch := segmenter.MaxMatch(text)
+		if strings.Join(maxMatch, "") != text {
+			t.Fatalf("MaxMatch: words %+v don't make up %s, seed: %d", maxMatch, text, *seed)
+		}
+		offset := 0
+		for _, word := range maxMatch {
+			if key, _, ok := tree.GetBestMatch(text[offset:]); ok && key != word {
+				printActions(actions)
+				printRTree(tree)
+				t.Fatalf("MaxMatch: got %s expect %s, seed: %d", word, key, *seed)
+			}
+			offset += len(word)
+		}
+
+		if viterbi := segmenter.Viterbi(text); strings.Join(viterbi, "") != text {
+			t.Fatalf("Viterbi: words %+v don't make up %s, seed: %d", viterbi, text, *seed)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package qradix
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrLookahead = errors.New("runes after the match were consumed and can not be unread")
+)
+
+// peekDiscarder is a reader which can look ahead and skip bytes, e.g. bufio.Reader
+type peekDiscarder interface {
+	peeker
+	Discard(n int) (int, error)
+}
+
+// MatchReader consumes the longest stored key from the reader and returns it with its value
+// and its length in bytes.
+// It returns io.EOF if the reader is empty, and ErrNotExist if no key matches the beginning of the input.
+//
+// If the reader supports Peek and Discard (e.g. bufio.Reader), runes are looked ahead
+// and only the matched key is consumed, but keys longer than the buffer of the reader are not matched.
+// Otherwise runes are read one by one and only the rune stopping the walk can be unread,
+// so if the walk along the tree passes the longest match (e.g. "ab" and "abcd" are stored and "abcx" is read),
+// the match is returned with ErrLookahead and the runes passed are lost.
+//
+// The tree is not locked while reading, so a slow reader doesn't block writers of the tree.
+// Instead the tree is read locked in each step, and if the tree is changed between steps,
+// the runes read so far are walked from the root again.
+// The walk stops once no longer key can be matched, so it doesn't wait for more input after such a key.
+func (T *RTree) MatchReader(reader io.RuneScanner) (string, interface{}, int, error) {
+	if pd, ok := reader.(peekDiscarder); ok {
+		return T.matchPeeker(pd)
+	}
+
+	sc := T.newStreamCursor()
+	matched, read, unmatchedRunes := 0, 0, 0
+	var matchedVal interface{}
+	for {
+		r, _, err := reader.ReadRune()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return sc.read.String()[:matched], matchedVal, matched, err
+		}
+		read++
+		along, val, ok, more := sc.step(r)
+		if !along {
+			// only the last rune can be unread
+			if reader.UnreadRune() != nil {
+				unmatchedRunes++
+			}
+			break
+		}
+		unmatchedRunes++
+
+		if ok {
+			matched, matchedVal, unmatchedRunes = sc.read.Len(), val, 0
+		}
+		if !more {
+			break
+		}
+	}
+	if read == 0 {
+		return "", nil, 0, io.EOF
+	}
+
+	var err error
+	if unmatchedRunes > 0 {
+		err = ErrLookahead
+	} else if matched == 0 {
+		err = ErrNotExist
+	}
+	if matched == 0 {
+		return "", nil, 0, err
+	}
+	return sc.read.String()[:matched], matchedVal, matched, err
+}
+
+// matchPeeker looks ahead for the longest match and only consumes the matched key
+func (T *RTree) matchPeeker(reader peekDiscarder) (string, interface{}, int, error) {
+	sc := T.newStreamCursor()
+	offset, matched := 0, 0
+	var matchedVal interface{}
+	for {
+		r, size, err := peekRune(reader, offset)
+		if err == io.EOF || err == bufio.ErrBufferFull {
+			break
+		} else if err != nil {
+			return "", nil, 0, err
+		}
+		along, val, ok, more := sc.step(r)
+		if !along {
+			break
+		}
+		offset += size
+		if ok {
+			matched, matchedVal = offset, val
+		}
+		if !more {
+			break
+		}
+	}
+
+	if matched == 0 {
+		if buf, err := reader.Peek(1); len(buf) == 0 {
+			if err == nil {
+				err = io.EOF
+			}
+			return "", nil, 0, err
+		}
+		return "", nil, 0, ErrNotExist
+	}
+
+	buf, _ := reader.Peek(matched)
+	key := string(buf)
+	if _, err := reader.Discard(matched); err != nil {
+		return "", nil, 0, err
+	}
+	return key, matchedVal, matched, nil
+}
+
+// peekRune peeks the rune at the offset,
+// bytes are peeked one by one until the rune is complete, so it doesn't wait for bytes after the rune
+func peekRune(reader peeker, offset int) (rune, int, error) {
+	for n := offset + 1; ; n++ {
+		buf, err := reader.Peek(n)
+		if len(buf) > offset && (utf8.FullRune(buf[offset:]) || err == io.EOF) {
+			// an incomplete rune at the end of the input is decoded as utf8.RuneError
+			r, size := utf8.DecodeRune(buf[offset:])
+			return r, size, nil
+		} else if err != nil {
+			return 0, 0, err
+		}
+	}
+}
+
+// streamCursor is a cursor moved with the tree read locked in each step,
+// it keeps runes stepped so far, so it can walk from the root again if the tree is changed between steps.
+type streamCursor struct {
+	T       *RTree
+	c       cursor
+	version uint64
+	read    strings.Builder
+}
+
+func (T *RTree) newStreamCursor() *streamCursor {
+	T.m.RLock()
+	defer T.m.RUnlock()
+	return &streamCursor{T: T, c: newCursor(T), version: T.version}
+}
+
+// step moves the cursor forward with the rune r, it returns false if there is no edge for r.
+// It also returns the value if a key ends at r, and false for more if no longer key can be matched.
+func (sc *streamCursor) step(r rune) (along bool, val interface{}, ok bool, more bool) {
+	sc.T.m.RLock()
+	defer sc.T.m.RUnlock()
+
+	if sc.version != sc.T.version {
+		sc.c, sc.version = newCursor(sc.T), sc.T.version
+		if !sc.c.stepString(sc.read.String()) {
+			return false, nil, false, false
+		}
+	}
+	if !sc.c.step(r) {
+		return false, nil, false, false
+	}
+	sc.read.WriteRune(r)
+	val, ok = sc.c.value()
+	return true, val, ok, sc.c.more()
+}
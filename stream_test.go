@@ -0,0 +1,180 @@
+package qradix
+
+import (
+	"bufio"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchReader(t *testing.T) {
+	t.Run("test MatchReader", testMatchReader)
+	t.Run("test MatchReader with random keys", testMatchReaderWithRandomKeys)
+	t.Run("test MatchReader with a slow reader", testMatchReaderWithSlowReader)
+	t.Run("test MatchReader with a live stream", testMatchReaderWithLiveStream)
+}
+
+func testMatchReader(t *testing.T) {
+	tree := NewRTree()
+	for _, key := range []string{"GET", "GET_ALL", "SET", " ", "世界"} {
+		tree.Insert(key, strings.ToLower(key))
+	}
+
+	// tokenize a stream
+	reader := bufio.NewReader(strings.NewReader("GET SET 世界GET_ALX"))
+	tokens := []string{}
+	for {
+		key, val, n, err := tree.MatchReader(reader)
+		if err == io.EOF {
+			break
+		} else if err == ErrNotExist {
+			r, _, _ := reader.ReadRune()
+			tokens = append(tokens, "?"+string(r))
+			continue
+		} else if err != nil || n != len(key) || val.(string) != strings.ToLower(key) {
+			t.Fatalf("MatchReader: got %s %v %d %v", key, val, n, err)
+		}
+		tokens = append(tokens, key)
+	}
+	if got := strings.Join(tokens, "|"); got != "GET| |SET| |世界|GET|?_|?A|?L|?X" {
+		t.Fatalf("MatchReader: got %s", got)
+	}
+
+	cases := []struct {
+		input string
+		key   string
+		rest  string
+		err   error
+	}{
+		{"GETX", "GET", "X", nil},
+		{"GET", "GET", "", nil},
+		{"GET_", "GET", "", ErrLookahead},
+		{"GET_ALX", "GET", "X", ErrLookahead},
+		{"GEX", "", "X", ErrLookahead},
+		{"世界世界", "世界", "世界", nil},
+		{"X", "", "X", ErrNotExist},
+		{"GE", "", "", ErrLookahead},
+		{"", "", "", io.EOF},
+	}
+	for _, tc := range cases {
+		// strings.Reader can only unread one rune
+		reader := strings.NewReader(tc.input)
+		key, _, n, err := tree.MatchReader(reader)
+		rest := tc.input[len(tc.input)-reader.Len():]
+		if key != tc.key || n != len(tc.key) || err != tc.err || rest != tc.rest {
+			t.Errorf("MatchReader(%s): got %s %d %v %s expect %s %v %s", tc.input, key, n, err, rest, tc.key, tc.err, tc.rest)
+		}
+	}
+}
+
+func testMatchReaderWithRandomKeys(t *testing.T) {
+	seedRand()
+	for i := 0; i < *testRound; i++ {
+		var actions []string
+		tree := NewRTree()
+		dict := make(map[string]string)
+		randomStrings := GetTestStrings()
+
+		for j := 0; j < *actionCount; j++ {
+			doRandomAction(&actions, randomStrings[rand.Intn(len(randomStrings))], tree, dict)
+		}
+
+		for j := 0; j < *actionCount; j++ {
+			text := randomStrings[rand.Intn(len(randomStrings))] + randomStrings[rand.Intn(len(randomStrings))]
+			expected, _, ok := tree.GetBestMatch(text)
+
+			reader := bufio.NewReader(strings.NewReader(text))
+			key, val, _, err := tree.MatchReader(reader)
+			if !ok && err != ErrNotExist || ok && (err != nil || key != expected || val.(string) != dict[key]) {
+				printActions(actions)
+				printRTree(tree)
+				t.Fatalf("MatchReader(%s): got %s %v expect %s, seed: %d", text, key, err, expected, *seed)
+			}
+			if rest, _ := reader.ReadString(0); rest != text[len(key):] {
+				t.Fatalf("MatchReader(%s): got rest %s, seed: %d", text, rest, *seed)
+			}
+		}
+	}
+}
+
+// runeScanner hides Peek and Discard of the reader
+type runeScanner struct {
+	io.RuneScanner
+}
+
+func testMatchReaderWithSlowReader(t *testing.T) {
+	tree := NewRTree()
+	tree.Insert("GET", "get")
+
+	for _, peekable := range []bool{true, false} {
+		pipeReader, pipeWriter := io.Pipe()
+		var reader io.RuneScanner = bufio.NewReader(pipeReader)
+		if !peekable {
+			reader = runeScanner{reader}
+		}
+		keys := make(chan string, 1)
+		go func() {
+			key, _, _, _ := tree.MatchReader(reader)
+			keys <- key
+		}()
+		// MatchReader has started when the first byte is read
+		pipeWriter.Write([]byte("G"))
+
+		// writers are not blocked while MatchReader is waiting for the input,
+		// and the node being walked is split
+		inserted := make(chan struct{})
+		go func() {
+			tree.Insert("GE", "ge")
+			close(inserted)
+		}()
+		select {
+		case <-inserted:
+		case <-time.After(10 * time.Second):
+			t.Fatal("MatchReader: writers are blocked by the reader")
+		}
+
+		pipeWriter.Write([]byte("ETX"))
+		pipeWriter.Close()
+		if key := <-keys; key != "GET" {
+			t.Errorf("MatchReader: got %s expect GET", key)
+		}
+	}
+}
+
+func testMatchReaderWithLiveStream(t *testing.T) {
+	tree := NewRTree()
+	for _, key := range []string{"PING", "世界"} {
+		tree.Insert(key, strings.ToLower(key))
+	}
+
+	for _, peekable := range []bool{true, false} {
+		for _, input := range []string{"PING", "世界"} {
+			// the connection is kept open after the key is sent
+			server, client := net.Pipe()
+			go client.Write([]byte(input))
+
+			var reader io.RuneScanner = bufio.NewReader(server)
+			if !peekable {
+				reader = runeScanner{reader}
+			}
+			keys := make(chan string, 1)
+			go func() {
+				key, _, _, _ := tree.MatchReader(reader)
+				keys <- key
+			}()
+			select {
+			case key := <-keys:
+				if key != input {
+					t.Errorf("MatchReader: got %s expect %s", key, input)
+				}
+			case <-time.After(10 * time.Second):
+				t.Errorf("MatchReader: it waits for more input after %s", input)
+			}
+			server.Close()
+			client.Close()
+		}
+	}
+}
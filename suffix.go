@@ -0,0 +1,220 @@
+package qradix
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// maxGram is the max number of runes of n-grams in SuffixIndex
+const maxGram = 3
+
+// SuffixIndex is a radix tree with indexes for suffix and substring queries.
+// Besides the forward tree, it keeps a tree of reversed keys for suffix queries,
+// and an index from n-grams (up to 3 runes) of keys to keys for substring queries.
+// The indexes are updated on every Insert and Remove.
+// SuffixIndex is safe for concurrent use.
+type SuffixIndex struct {
+	m        sync.RWMutex
+	forward  *RTree
+	reversed *RTree                         // reversed keys, values are the original keys
+	grams    map[string]map[string]struct{} // n-gram -> keys containing it
+}
+
+// NewSuffixIndex returns an empty SuffixIndex
+func NewSuffixIndex() *SuffixIndex {
+	return &SuffixIndex{
+		forward:  NewRTree(),
+		reversed: NewRTree(),
+		grams:    map[string]map[string]struct{}{},
+	}
+}
+
+// reverseRunes reverses the string rune by rune, and invalid bytes are kept as they are
+func reverseRunes(s string) string {
+	reversed := make([]byte, len(s))
+	end := len(s)
+	for len(s) > 0 {
+		_, size := utf8.DecodeRuneInString(s)
+		copy(reversed[end-size:end], s[:size])
+		end -= size
+		s = s[size:]
+	}
+	return string(reversed)
+}
+
+// nGrams returns all distinct substrings of the key with 1 to n runes
+func nGrams(key string, n int) map[string]struct{} {
+	offsets := []int{}
+	for offset := range key {
+		offsets = append(offsets, offset)
+	}
+	offsets = append(offsets, len(key))
+
+	grams := map[string]struct{}{}
+	for i := 0; i < len(offsets)-1; i++ {
+		for j := i + 1; j < len(offsets) && j <= i+n; j++ {
+			grams[key[offsets[i]:offsets[j]]] = struct{}{}
+		}
+	}
+	return grams
+}
+
+// Insert stores the value with the key and updates indexes, the old value is returned if the key exists
+func (S *SuffixIndex) Insert(key string, val interface{}) (interface{}, error) {
+	S.m.Lock()
+	defer S.m.Unlock()
+
+	_, err := S.forward.Get(key)
+	exists := err == nil
+	oldVal, err := S.forward.Insert(key, val)
+	if err != nil || exists {
+		return oldVal, err
+	}
+
+	if _, err = S.reversed.Insert(reverseRunes(key), key); err != nil {
+		return nil, err
+	}
+	for gram := range nGrams(key, maxGram) {
+		keys, ok := S.grams[gram]
+		if !ok {
+			keys = map[string]struct{}{}
+			S.grams[gram] = keys
+		}
+		keys[key] = struct{}{}
+	}
+	return oldVal, nil
+}
+
+// Get returns the value of the key
+func (S *SuffixIndex) Get(key string) (interface{}, error) {
+	S.m.RLock()
+	defer S.m.RUnlock()
+
+	return S.forward.Get(key)
+}
+
+// Remove removes the key and updates indexes, it returns false if the key doesn't exist
+func (S *SuffixIndex) Remove(key string) bool {
+	S.m.Lock()
+	defer S.m.Unlock()
+
+	if !S.forward.Remove(key) {
+		return false
+	}
+	S.reversed.Remove(reverseRunes(key))
+	for gram := range nGrams(key, maxGram) {
+		delete(S.grams[gram], key)
+		if len(S.grams[gram]) == 0 {
+			delete(S.grams, gram)
+		}
+	}
+	return true
+}
+
+// Size returns the number of keys
+func (S *SuffixIndex) Size() int {
+	S.m.RLock()
+	defer S.m.RUnlock()
+
+	return S.forward.Size()
+}
+
+// HasSuffix returns true if any key ends with the suffix
+func (S *SuffixIndex) HasSuffix(suffix string) bool {
+	S.m.RLock()
+	defer S.m.RUnlock()
+
+	return S.reversed.CountPrefix(reverseRunes(suffix)) > 0
+}
+
+// KeysWithSuffix returns at most limit keys ending with the suffix and their values,
+// in lexicographic order of reversed keys.
+func (S *SuffixIndex) KeysWithSuffix(suffix string, limit int) []KV {
+	S.m.RLock()
+	defer S.m.RUnlock()
+
+	items, _ := S.reversed.Page(reverseRunes(suffix), "", limit)
+	for i, item := range items {
+		key := item.Val.(string)
+		val, _ := S.forward.Get(key)
+		items[i] = KV{Key: key, Val: val}
+	}
+	return items
+}
+
+// Contains returns true if any key contains the substring,
+// it returns as soon as a candidate key is found containing the substring.
+func (S *SuffixIndex) Contains(sub string) bool {
+	S.m.RLock()
+	defer S.m.RUnlock()
+
+	if len(sub) == 0 {
+		return S.forward.Size() > 0
+	}
+	for key := range S.candidates(sub) {
+		if strings.Contains(key, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// KeysContaining returns at most limit keys containing the substring and their values in lexicographic order.
+// Keys are looked up in the n-gram index, and for substrings longer than 3 runes,
+// keys containing the rarest 3-rune gram of the substring are checked.
+// The substring is matched on rune boundaries of keys.
+func (S *SuffixIndex) KeysContaining(sub string, limit int) []KV {
+	S.m.RLock()
+	defer S.m.RUnlock()
+
+	if limit <= 0 {
+		return []KV{}
+	} else if len(sub) == 0 {
+		items, _ := S.forward.Page("", "", limit)
+		return items
+	}
+
+	keys := []string{}
+	for key := range S.candidates(sub) {
+		if strings.Contains(key, sub) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+
+	items := []KV{}
+	for _, key := range keys {
+		val, _ := S.forward.Get(key)
+		items = append(items, KV{Key: key, Val: val})
+	}
+	return items
+}
+
+// candidates returns keys which may contain the non-empty substring in the n-gram index,
+// they are keys of the substring itself if it is not longer than 3 runes,
+// or keys of the rarest 3-rune gram of the substring.
+func (S *SuffixIndex) candidates(sub string) map[string]struct{} {
+	if utf8.RuneCountInString(sub) <= maxGram {
+		return S.grams[sub]
+	}
+
+	var candidates map[string]struct{}
+	for gram := range nGrams(sub, maxGram) {
+		if utf8.RuneCountInString(gram) < maxGram {
+			continue
+		}
+		keys := S.grams[gram]
+		if candidates == nil || len(keys) < len(candidates) {
+			candidates = keys
+		}
+		if len(candidates) == 0 {
+			break
+		}
+	}
+	return candidates
+}
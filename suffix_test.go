@@ -0,0 +1,128 @@
+package qradix
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestSuffixIndex(t *testing.T) {
+	t.Run("test suffix and substring queries", testSuffixIndex)
+	t.Run("test SuffixIndex with random keys", testSuffixIndexWithRandomKeys)
+}
+
+func kvKeys(items []KV) string {
+	keys := []string{}
+	for _, item := range items {
+		keys = append(keys, item.Key)
+	}
+	return strings.Join(keys, ",")
+}
+
+func testSuffixIndex(t *testing.T) {
+	index := NewSuffixIndex()
+	for _, key := range []string{"a.json", "b.json", "ajson", "error.log", "app-error.txt", "世界.json", "json"} {
+		index.Insert(key, key)
+	}
+	index.Insert("json", "JSON")
+
+	if !index.HasSuffix(".json") || !index.HasSuffix("") || index.HasSuffix(".yaml") {
+		t.Error("HasSuffix: incorrect result")
+	}
+	if got := kvKeys(index.KeysWithSuffix("json", 10)); got != "json,a.json,b.json,世界.json,ajson" {
+		t.Errorf("KeysWithSuffix: got %s", got)
+	}
+	if got := kvKeys(index.KeysWithSuffix(".json", 2)); got != "a.json,b.json" {
+		t.Errorf("KeysWithSuffix: got %s", got)
+	}
+
+	cases := map[string]string{
+		"error":  "app-error.txt,error.log",
+		"rro":    "app-error.txt,error.log",
+		"界.js":   "世界.json",
+		"界":      "世界.json",
+		"json":   "a.json,ajson,b.json,json,世界.json",
+		"r.l":    "error.log",
+		"errors": "",
+		"":       "a.json,ajson,app-error.txt,b.json,error.log,json,世界.json",
+	}
+	for sub, expected := range cases {
+		if got := kvKeys(index.KeysContaining(sub, 10)); got != expected {
+			t.Errorf("KeysContaining(%s): got %s expect %s", sub, got, expected)
+		}
+		if index.Contains(sub) != (expected != "") {
+			t.Errorf("Contains(%s): incorrect result", sub)
+		}
+	}
+	if items := index.KeysContaining("json", 1); len(items) != 1 || items[0].Key != "a.json" {
+		t.Errorf("KeysContaining: got %+v with limit", items)
+	}
+	if val, err := index.Get("json"); err != nil || val.(string) != "JSON" {
+		t.Errorf("Get: got %v %v", val, err)
+	}
+
+	if !index.Remove("error.log") || index.Remove("error.log") || index.Size() != 6 {
+		t.Fatal("Remove: failed to remove")
+	}
+	if index.Contains("log") || index.HasSuffix(".log") {
+		t.Fatal("Remove: indexes are not updated")
+	}
+}
+
+func testSuffixIndexWithRandomKeys(t *testing.T) {
+	seedRand()
+	for i := 0; i < *testRound; i++ {
+		index := NewSuffixIndex()
+		dict := make(map[string]string)
+		randomStrings := GetTestStrings()
+
+		for j := 0; j < *actionCount; j++ {
+			key := randomStrings[rand.Intn(len(randomStrings))]
+			if rand.Intn(3) == 0 {
+				index.Remove(key)
+				delete(dict, key)
+			} else {
+				index.Insert(key, key)
+				dict[key] = key
+			}
+		}
+
+		keys := []string{}
+		for key := range dict {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for j := 0; j < *actionCount; j++ {
+			s := randomStrings[rand.Intn(len(randomStrings))]
+			runes := []rune(s)
+			start := rand.Intn(len(runes))
+			sub := string(runes[start : start+rand.Intn(len(runes)-start)+1])
+			suffix := string(runes[start:])
+
+			containing, hasSuffix := []string{}, false
+			for _, key := range keys {
+				if strings.Contains(key, sub) {
+					containing = append(containing, key)
+				}
+				hasSuffix = hasSuffix || strings.HasSuffix(key, suffix)
+			}
+
+			if got := kvKeys(index.KeysContaining(sub, len(keys)+1)); got != strings.Join(containing, ",") {
+				t.Fatalf("KeysContaining(%s): got %s expect %s, seed: %d", sub, got, containing, *seed)
+			}
+			if index.Contains(sub) != (len(containing) > 0) {
+				t.Fatalf("Contains(%s): should be %t, seed: %d", sub, len(containing) > 0, *seed)
+			}
+			if index.HasSuffix(suffix) != hasSuffix {
+				t.Fatalf("HasSuffix(%s): should be %t, seed: %d", suffix, hasSuffix, *seed)
+			}
+			for _, item := range index.KeysWithSuffix(suffix, len(keys)+1) {
+				if !strings.HasSuffix(item.Key, suffix) || item.Val.(string) != dict[item.Key] {
+					t.Fatalf("KeysWithSuffix(%s): invalid %+v, seed: %d", suffix, item, *seed)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package qradix
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrUnexpectedMessage = errors.New("unexpected sync message")
+)
+
+const (
+	syncView = iota // request for the value and children of a path
+	syncDump        // request for all keys and values under a path
+	syncDone        // the synchronization is finished
+)
+
+// SyncChild is a child of the requested path
+type SyncChild struct {
+	Segment string
+	Hash    []byte
+}
+
+// SyncMessage is a request or a response exchanged during synchronization
+type SyncMessage struct {
+	Type     int
+	Path     string
+	HasLeaf  bool
+	Value    interface{}
+	Children []SyncChild
+	Items    []KV
+	Err      string
+}
+
+// SyncTransport sends and receives messages between two peers
+// Recv returns io.EOF if the peer is gone.
+type SyncTransport interface {
+	Send(msg *SyncMessage) error
+	Recv() (*SyncMessage, error)
+}
+
+type memTransport struct {
+	send chan<- *SyncMessage
+	recv <-chan *SyncMessage
+}
+
+// NewMemTransports returns two connected transports for peers in the same process
+func NewMemTransports() (SyncTransport, SyncTransport) {
+	aToB := make(chan *SyncMessage, 1)
+	bToA := make(chan *SyncMessage, 1)
+	return &memTransport{send: aToB, recv: bToA}, &memTransport{send: bToA, recv: aToB}
+}
+
+func (tr *memTransport) Send(msg *SyncMessage) error {
+	tr.send <- msg
+	return nil
+}
+
+func (tr *memTransport) Recv() (*SyncMessage, error) {
+	msg, ok := <-tr.recv
+	if !ok {
+		return nil, io.EOF
+	}
+	return msg, nil
+}
+
+type connTransport struct {
+	enc *gob.Encoder
+	dec *gob.Decoder
+}
+
+// NewConnTransport returns a transport which encodes messages by gob over conn.
+// Values are sent as interface{}, so types other than basic types must be registered by gob.Register.
+func NewConnTransport(conn net.Conn) SyncTransport {
+	return &connTransport{
+		enc: gob.NewEncoder(conn),
+		dec: gob.NewDecoder(conn),
+	}
+}
+
+func (tr *connTransport) Send(msg *SyncMessage) error {
+	return tr.enc.Encode(msg)
+}
+
+func (tr *connTransport) Recv() (*SyncMessage, error) {
+	msg := &SyncMessage{}
+	if err := tr.dec.Decode(msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// ServeSync answers requests from a peer which is synchronizing from T by SyncFrom,
+// it returns when the peer finishes or the transport is closed.
+// The tree is only locked while a request is being answered.
+func (T *RTree) ServeSync(tr SyncTransport) error {
+	for {
+		req, err := tr.Recv()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var resp *SyncMessage
+		switch req.Type {
+		case syncView:
+			T.m.RLock()
+			if T.hashValue == nil {
+				resp = &SyncMessage{Type: syncView, Err: ErrHashingDisabled.Error()}
+			} else {
+				resp = T.view(req.Path)
+			}
+			T.m.RUnlock()
+		case syncDump:
+			T.m.RLock()
+			resp = &SyncMessage{Type: syncDump, Path: req.Path, Items: T.dump(req.Path)}
+			T.m.RUnlock()
+		case syncDone:
+			return nil
+		default:
+			resp = &SyncMessage{Type: req.Type, Err: ErrUnexpectedMessage.Error()}
+		}
+
+		if err = tr.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// SyncFrom makes T identical to the tree served by the peer with ServeSync.
+// Both trees exchange hashes from the top down and only different subtrees are transferred,
+// so hashing must be enabled in both trees with the same ValueHasher.
+// T is locked during synchronization.
+// The peer is told to finish even if the synchronization fails, so ServeSync returns too.
+func (T *RTree) SyncFrom(tr SyncTransport) (err error) {
+	T.m.Lock()
+	defer T.m.Unlock()
+	defer T.refresh()
+	defer func() {
+		if tr == nil {
+			return
+		}
+		// errors of sending are ignored if the synchronization already failed
+		if sendErr := tr.Send(&SyncMessage{Type: syncDone}); err == nil {
+			err = sendErr
+		}
+	}()
+
+	if T.hashValue == nil {
+		return ErrHashingDisabled
+	}
+	return T.syncPath(tr, "")
+}
+
+// syncPath makes keys starting with path in T identical to the remote ones,
+// the remote tree must have a node (or a part of a node) matching the path
+func (T *RTree) syncPath(tr SyncTransport, path string) error {
+	remote, err := request(tr, &SyncMessage{Type: syncView, Path: path})
+	if err != nil {
+		return err
+	}
+	// the local view is taken before nodes under the path are changed at this level,
+	// and subtrees listed in it are only changed by their own recursion after they are compared,
+	// so hashes in it are fresh without refreshing the tree for each request
+	local := T.view(path)
+
+	if len(path) > 0 {
+		if remote.HasLeaf &&
+			(!local.HasLeaf || !bytes.Equal(T.hashValue(local.Value), T.hashValue(remote.Value))) {
+			if _, err = T.insert(path, remote.Value); err != nil {
+				return err
+			}
+		} else if !remote.HasLeaf && local.HasLeaf {
+			T.remove(path)
+		}
+	}
+
+	localChildren := map[rune]SyncChild{}
+	for _, child := range local.Children {
+		localChildren[getRune1(child.Segment)] = child
+	}
+	for _, remoteChild := range remote.Children {
+		rune1 := getRune1(remoteChild.Segment)
+		localChild, ok := localChildren[rune1]
+		delete(localChildren, rune1)
+
+		if !ok {
+			// the remote subtree doesn't exist in T
+			dumped, err := request(tr, &SyncMessage{Type: syncDump, Path: path + remoteChild.Segment})
+			if err != nil {
+				return err
+			}
+			for _, item := range dumped.Items {
+				if _, err = T.insert(item.Key, item.Val); err != nil {
+					return err
+				}
+			}
+		} else if localChild.Segment == remoteChild.Segment && bytes.Equal(localChild.Hash, remoteChild.Hash) {
+			// identical subtrees
+			continue
+		} else {
+			offset := commonPrefixLen(localChild.Segment, remoteChild.Segment)
+			if err = T.syncPath(tr, path+remoteChild.Segment[:offset]); err != nil {
+				return err
+			}
+		}
+	}
+	for _, localChild := range localChildren {
+		// the local subtree doesn't exist in the remote tree
+		T.removePrefix(path + localChild.Segment)
+	}
+	return nil
+}
+
+func request(tr SyncTransport, req *SyncMessage) (*SyncMessage, error) {
+	if err := tr.Send(req); err != nil {
+		return nil, err
+	}
+	resp, err := tr.Recv()
+	if err != nil {
+		return nil, err
+	} else if resp.Err != "" {
+		return nil, errors.New(resp.Err)
+	} else if resp.Type != req.Type {
+		return nil, fmt.Errorf("%w: type(%d) expected(%d)", ErrUnexpectedMessage, resp.Type, req.Type)
+	}
+	return resp, nil
+}
+
+// view returns the value and children of the path,
+// if the path ends in the middle of a node, the rest of the node is the only child.
+// hashes must be fresh.
+func (T *RTree) view(path string) *SyncMessage {
+	msg := &SyncMessage{Type: syncView, Path: path, Children: []SyncChild{}}
+
+	children := T.root
+	pathSuffix := path
+	for len(pathSuffix) > 0 {
+		if children == nil {
+			return msg
+		}
+		rune1, _ := utf8.DecodeRuneInString(pathSuffix)
+		matchedNode, ok := children.Idx[rune1]
+		if !ok {
+			return msg
+		}
+
+		if strings.HasPrefix(matchedNode.Prefix, pathSuffix) && len(matchedNode.Prefix) > len(pathSuffix) {
+			// the path ends in the middle of matchedNode
+			rest := matchedNode.Prefix[len(pathSuffix):]
+			var valueHash []byte
+			if matchedNode.Leaf != nil {
+				valueHash = T.hashValue(matchedNode.Leaf.Val)
+			}
+			msg.Children = append(msg.Children, SyncChild{
+				Segment: rest,
+				Hash: hashNode(
+					rest,
+					matchedNode.Leaf != nil,
+					valueHash,
+					hashLevel(levelHashes(matchedNode.Children)),
+				),
+			})
+			return msg
+		} else if !strings.HasPrefix(pathSuffix, matchedNode.Prefix) {
+			return msg
+		}
+
+		pathSuffix = pathSuffix[len(matchedNode.Prefix):]
+		if len(pathSuffix) == 0 && matchedNode.Leaf != nil {
+			msg.HasLeaf = true
+			msg.Value = matchedNode.Leaf.Val
+		}
+		children = matchedNode.Children
+	}
+
+	for _, child := range sortedLevel(children) {
+		msg.Children = append(msg.Children, SyncChild{Segment: child.Prefix, Hash: child.hash})
+	}
+	return msg
+}
+
+// dump returns all keys and values starting with the prefix in lexicographic order
+func (T *RTree) dump(prefix string) []KV {
+	items := []KV{}
+	nodes, base := T.findPrefix(prefix)
+	for _, n := range nodes {
+		walkSorted(n, base, func(key string, val interface{}) {
+			items = append(items, KV{Key: key, Val: val})
+		})
+	}
+	return items
+}
@@ -0,0 +1,149 @@
+package qradix
+
+import (
+	"bytes"
+	"math/rand"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSync(t *testing.T) {
+	t.Run("test SyncFrom", testSyncFrom)
+	t.Run("test SyncFrom with random keys", testSyncFromWithRandomKeys)
+	t.Run("test SyncFrom with errors", testSyncFromWithErrors)
+}
+
+// countingTransport counts sent messages
+type countingTransport struct {
+	SyncTransport
+	sent int
+}
+
+func (tr *countingTransport) Send(msg *SyncMessage) error {
+	tr.sent++
+	return tr.SyncTransport.Send(msg)
+}
+
+func syncInMem(t *testing.T, replica, source *RTree) int {
+	clientTr, serverTr := NewMemTransports()
+	counter := &countingTransport{SyncTransport: clientTr}
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- source.ServeSync(serverTr)
+	}()
+
+	if err := replica.SyncFrom(counter); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+	return counter.sent
+}
+
+func testSyncFrom(t *testing.T) {
+	source, replica := NewRTree(), NewRTree()
+	source.EnableHashing(StringValueHasher)
+	replica.EnableHashing(StringValueHasher)
+	for _, key := range []string{"a", "ab", "abc", "abd", "b", "ba", "中文", "中国"} {
+		source.Insert(key, key)
+		replica.Insert(key, key)
+	}
+
+	// identical trees only compare the first level
+	if sent := syncInMem(t, replica, source); sent != 2 {
+		t.Errorf("SyncFrom: identical trees should send 2 messages but sent %d", sent)
+	}
+
+	source.Insert("abe", "abe")
+	source.Remove("ba")
+	source.Insert("中文", "changed")
+	replica.Insert("x", "x")
+	replica.Insert("abcd", "abcd")
+	syncInMem(t, replica, source)
+	if !bytes.Equal(replica.RootHash(), source.RootHash()) {
+		t.Fatal("SyncFrom: replica is not identical to the source")
+	}
+	if changes := Diff(replica, source); len(changes) > 0 {
+		t.Fatalf("SyncFrom: replica is different from the source %+v", changes)
+	}
+
+	if err := NewRTree().SyncFrom(nil); err != ErrHashingDisabled {
+		t.Fatalf("SyncFrom: hashing should be disabled: %s", err)
+	}
+}
+
+func testSyncFromWithRandomKeys(t *testing.T) {
+	seedRand()
+	for i := 0; i < *testRound; i++ {
+		var actions []string
+		source, replica := NewRTree(), NewRTree()
+		source.EnableHashing(StringValueHasher)
+		replica.EnableHashing(StringValueHasher)
+		dict := make(map[string]string)
+		randomStrings := GetTestStrings()
+
+		for j := 0; j < *actionCount; j++ {
+			doRandomAction(&actions, randomStrings[rand.Intn(len(randomStrings))], source, dict)
+			doRandomAction(&actions, randomStrings[rand.Intn(len(randomStrings))], replica, nil)
+		}
+
+		if i%2 == 0 {
+			syncInMem(t, replica, source)
+		} else {
+			clientConn, serverConn := net.Pipe()
+			errChan := make(chan error, 1)
+			go func() {
+				errChan <- source.ServeSync(NewConnTransport(serverConn))
+				serverConn.Close()
+			}()
+			if err := replica.SyncFrom(NewConnTransport(clientConn)); err != nil {
+				t.Fatal(err)
+			}
+			if err := <-errChan; err != nil {
+				t.Fatal(err)
+			}
+			clientConn.Close()
+		}
+
+		if !isEqual(replica, dict) || replica.Size() != len(dict) {
+			printActions(actions)
+			printRTree(replica)
+			printMap(dict)
+			t.Fatalf("replica is not identical to Map, seed: %d", *seed)
+		}
+		if !bytes.Equal(replica.RootHash(), source.RootHash()) {
+			t.Fatalf("replica's RootHash is not identical to the source's, seed: %d", *seed)
+		}
+	}
+}
+
+func testSyncFromWithErrors(t *testing.T) {
+	hashed, unhashed := NewRTree(), NewRTree()
+	hashed.EnableHashing(StringValueHasher)
+	hashed.Insert("a", "a")
+	unhashed.Insert("b", "b")
+
+	// hashing is disabled in the replica or in the source
+	for _, pair := range [][2]*RTree{{unhashed, hashed}, {hashed, unhashed}} {
+		replica, source := pair[0], pair[1]
+		clientTr, serverTr := NewMemTransports()
+		errChan := make(chan error, 1)
+		go func() {
+			errChan <- source.ServeSync(serverTr)
+		}()
+
+		if err := replica.SyncFrom(clientTr); err == nil {
+			t.Fatal("SyncFrom: it should fail if hashing is disabled")
+		}
+		select {
+		case err := <-errChan:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("ServeSync: it should return after SyncFrom fails")
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package qradix
+
+import (
+	"sort"
+	"sync"
+)
+
+// TokenKV is a key of tokens and its value
+type TokenKV struct {
+	Key []string
+	Val interface{}
+}
+
+// tokenNode is a node of TokenTree, its children are indexed by their first tokens
+type tokenNode struct {
+	prefix   []string
+	children map[string]*tokenNode
+	leaf     *leafNode
+}
+
+// TokenTree is a radix tree whose keys are sequences of tokens (e.g. words of a phrase or a command path),
+// common prefixes are compressed token by token, so keys only match at token boundaries
+// and tokens need no escaping.
+// Keys are copied when they are stored, and keys returned are safe to be modified.
+// TokenTree is safe for concurrent use.
+type TokenTree struct {
+	root *tokenNode
+	size int
+	m    *sync.RWMutex
+}
+
+// NewTokenTree returns a new TokenTree
+func NewTokenTree() *TokenTree {
+	return &TokenTree{
+		root: &tokenNode{},
+		m:    &sync.RWMutex{},
+	}
+}
+
+// Size returns the number of keys
+func (T *TokenTree) Size() int {
+	T.m.RLock()
+	defer T.m.RUnlock()
+	return T.size
+}
+
+// joinTokens returns a new slice of tokens in t1 followed by tokens in t2
+func joinTokens(t1, t2 []string) []string {
+	joined := make([]string, 0, len(t1)+len(t2))
+	joined = append(joined, t1...)
+	return append(joined, t2...)
+}
+
+// commonTokens returns the number of common leading tokens of t1 and t2
+func commonTokens(t1, t2 []string) int {
+	i := 0
+	for i < len(t1) && i < len(t2) && t1[i] == t2[i] {
+		i++
+	}
+	return i
+}
+
+// Insert stores the value with the key, the old value is returned if the key exists
+func (T *TokenTree) Insert(key []string, val interface{}) (interface{}, error) {
+	if len(key) == 0 {
+		return nil, ErrEmptyKey
+	}
+	T.m.Lock()
+	defer T.m.Unlock()
+
+	parent := T.root
+	for {
+		child, ok := parent.children[key[0]]
+		if !ok {
+			if parent.children == nil {
+				parent.children = map[string]*tokenNode{}
+			}
+			parent.children[key[0]] = &tokenNode{prefix: joinTokens(key, nil), leaf: &leafNode{Val: val}}
+			T.size++
+			return nil, nil
+		}
+
+		common := commonTokens(child.prefix, key)
+		if common < len(child.prefix) {
+			// split the child
+			mid := &tokenNode{
+				prefix:   joinTokens(child.prefix[:common], nil),
+				children: map[string]*tokenNode{child.prefix[common]: child},
+			}
+			child.prefix = joinTokens(child.prefix[common:], nil)
+			parent.children[key[0]] = mid
+			child = mid
+		}
+
+		key = key[common:]
+		if len(key) == 0 {
+			if child.leaf != nil {
+				oldVal := child.leaf.Val
+				child.leaf.Val = val
+				return oldVal, nil
+			}
+			child.leaf = &leafNode{Val: val}
+			T.size++
+			return nil, nil
+		}
+		parent = child
+	}
+}
+
+// find returns the path of nodes from the root to the node matching the key exactly,
+// it returns nil if there is no such node
+func (T *TokenTree) find(key []string) []*tokenNode {
+	path := []*tokenNode{T.root}
+	n := T.root
+	for len(key) > 0 {
+		child, ok := n.children[key[0]]
+		if !ok || len(key) < len(child.prefix) || commonTokens(child.prefix, key) < len(child.prefix) {
+			return nil
+		}
+		key = key[len(child.prefix):]
+		path = append(path, child)
+		n = child
+	}
+	return path
+}
+
+// Get returns the value of the key, it returns ErrNotExist if the key doesn't exist
+func (T *TokenTree) Get(key []string) (interface{}, error) {
+	T.m.RLock()
+	defer T.m.RUnlock()
+
+	path := T.find(key)
+	if len(path) < 2 || path[len(path)-1].leaf == nil {
+		return nil, ErrNotExist
+	}
+	return path[len(path)-1].leaf.Val, nil
+}
+
+// Remove removes the key, it returns false if the key doesn't exist
+func (T *TokenTree) Remove(key []string) bool {
+	T.m.Lock()
+	defer T.m.Unlock()
+
+	path := T.find(key)
+	if len(path) < 2 || path[len(path)-1].leaf == nil {
+		return false
+	}
+	n, parent := path[len(path)-1], path[len(path)-2]
+	n.leaf = nil
+	T.size--
+
+	if len(n.children) == 0 {
+		delete(parent.children, n.prefix[0])
+		if parent != T.root && parent.leaf == nil && len(parent.children) == 1 {
+			mergeTokenNode(parent)
+		}
+	} else if len(n.children) == 1 {
+		mergeTokenNode(n)
+	}
+	return true
+}
+
+// mergeTokenNode merges the only child of n into n
+func mergeTokenNode(n *tokenNode) {
+	for _, child := range n.children {
+		n.prefix = joinTokens(n.prefix, child.prefix)
+		n.children = child.children
+		n.leaf = child.leaf
+	}
+}
+
+// PrefixMatches returns all stored keys which are prefixes of the key (including itself)
+// with their values, from the shortest to the longest
+func (T *TokenTree) PrefixMatches(key []string) []TokenKV {
+	T.m.RLock()
+	defer T.m.RUnlock()
+
+	matches := []TokenKV{}
+	n, matched := T.root, 0
+	for matched < len(key) {
+		child, ok := n.children[key[matched]]
+		if !ok || commonTokens(child.prefix, key[matched:]) < len(child.prefix) {
+			break
+		}
+		matched += len(child.prefix)
+		if child.leaf != nil {
+			matches = append(matches, TokenKV{Key: joinTokens(key[:matched], nil), Val: child.leaf.Val})
+		}
+		n = child
+	}
+	return matches
+}
+
+// GetBestMatch returns the longest stored key which is a prefix of the key (including itself)
+// if there is no match, it returns nil, nil and false
+func (T *TokenTree) GetBestMatch(key []string) ([]string, interface{}, bool) {
+	matches := T.PrefixMatches(key)
+	if len(matches) == 0 {
+		return nil, nil, false
+	}
+	best := matches[len(matches)-1]
+	return best.Key, best.Val, true
+}
+
+// GetLongerMatches returns at most limit stored keys which start with the key and are longer than it,
+// in lexicographic order of tokens
+func (T *TokenTree) GetLongerMatches(key []string, limit int) []TokenKV {
+	T.m.RLock()
+	defer T.m.RUnlock()
+
+	matches := []TokenKV{}
+	if limit <= 0 {
+		return matches
+	}
+
+	n, base, rest := T.root, []string{}, key
+	for len(rest) > 0 {
+		child, ok := n.children[rest[0]]
+		if !ok {
+			return matches
+		}
+		common := commonTokens(child.prefix, rest)
+		if common < len(rest) && common < len(child.prefix) {
+			return matches
+		}
+		base = joinTokens(base, child.prefix)
+		rest = rest[common:]
+		n = child
+	}
+
+	if len(base) > len(key) && n.leaf != nil {
+		// the key ends in the middle of n
+		matches = append(matches, TokenKV{Key: base, Val: n.leaf.Val})
+	}
+	walkTokens(n, base, func(key []string, val interface{}) bool {
+		matches = append(matches, TokenKV{Key: key, Val: val})
+		return len(matches) < limit
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// Walk calls fn with all keys and values in lexicographic order of tokens until fn returns false
+func (T *TokenTree) Walk(fn func(key []string, val interface{}) bool) {
+	T.m.RLock()
+	defer T.m.RUnlock()
+
+	walkTokens(T.root, []string{}, fn)
+}
+
+// walkTokens calls fn with keys under n (excluding n itself) in order,
+// base is the key of n, and it returns false if fn returns false
+func walkTokens(n *tokenNode, base []string, fn func(key []string, val interface{}) bool) bool {
+	firstTokens := make([]string, 0, len(n.children))
+	for token := range n.children {
+		firstTokens = append(firstTokens, token)
+	}
+	sort.Strings(firstTokens)
+
+	for _, token := range firstTokens {
+		child := n.children[token]
+		key := joinTokens(base, child.prefix)
+		if child.leaf != nil && !fn(joinTokens(key, nil), child.leaf.Val) {
+			return false
+		}
+		if !walkTokens(child, key, fn) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,176 @@
+package qradix
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestTokenTree(t *testing.T) {
+	t.Run("test TokenTree", testTokenTree)
+	t.Run("test TokenTree with random keys", testTokenTreeWithRandomKeys)
+}
+
+func tokenKeys(items []TokenKV) string {
+	keys := []string{}
+	for _, item := range items {
+		keys = append(keys, strings.Join(item.Key, " "))
+	}
+	return strings.Join(keys, ",")
+}
+
+func testTokenTree(t *testing.T) {
+	tree := NewTokenTree()
+	keys := [][]string{
+		{"git"},
+		{"git", "remote"},
+		{"git", "remote", "add"},
+		{"git", "remote", "remove"},
+		{"git", "commit"},
+		{"go", "test"},
+		{"gi"},
+	}
+	for _, key := range keys {
+		if _, err := tree.Insert(key, strings.Join(key, " ")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := tree.Insert([]string{}, nil); err != ErrEmptyKey {
+		t.Fatal("Insert: empty key should be rejected")
+	}
+	if oldVal, _ := tree.Insert([]string{"git"}, "GIT"); oldVal.(string) != "git" || tree.Size() != len(keys) {
+		t.Fatalf("Insert: got old value %v and size %d", oldVal, tree.Size())
+	}
+
+	if val, err := tree.Get([]string{"git", "remote"}); err != nil || val.(string) != "git remote" {
+		t.Errorf("Get: got %v %v", val, err)
+	}
+	for _, key := range [][]string{{"go"}, {"git", "rem"}, {"git", "remote", "add", "x"}, {}} {
+		if _, err := tree.Get(key); err != ErrNotExist {
+			t.Errorf("Get(%v): should not exist", key)
+		}
+	}
+
+	if got := tokenKeys(tree.PrefixMatches([]string{"git", "remote", "add", "origin"})); got != "git,git remote,git remote add" {
+		t.Errorf("PrefixMatches: got %s", got)
+	}
+	if key, val, ok := tree.GetBestMatch([]string{"git", "remote", "set-url"}); !ok ||
+		strings.Join(key, " ") != "git remote" || val.(string) != "git remote" {
+		t.Errorf("GetBestMatch: got %v %v %t", key, val, ok)
+	}
+	if _, _, ok := tree.GetBestMatch([]string{"git-lfs"}); ok {
+		t.Error("GetBestMatch: tokens should not match partially")
+	}
+
+	cases := map[string]string{
+		"git":        "git commit,git remote,git remote add,git remote remove",
+		"git remote": "git remote add,git remote remove",
+		"go":         "go test",
+		"":           "gi,git,git commit,git remote,git remote add,git remote remove,go test",
+		"go test":    "",
+		"hg":         "",
+	}
+	for key, expected := range cases {
+		tokens := strings.Fields(key)
+		if got := tokenKeys(tree.GetLongerMatches(tokens, 10)); got != expected {
+			t.Errorf("GetLongerMatches(%s): got %s expect %s", key, got, expected)
+		}
+	}
+	if got := tokenKeys(tree.GetLongerMatches([]string{"git"}, 2)); got != "git commit,git remote" {
+		t.Errorf("GetLongerMatches: got %s with limit", got)
+	}
+
+	if !tree.Remove([]string{"git", "remote"}) || tree.Remove([]string{"git", "remote"}) || tree.Remove([]string{"git", "rem"}) {
+		t.Fatal("Remove: incorrect result")
+	}
+	tree.Remove([]string{"git", "remote", "add"})
+	if val, err := tree.Get([]string{"git", "remote", "remove"}); err != nil || val.(string) != "git remote remove" {
+		t.Fatalf("Get: got %v %v after removing", val, err)
+	}
+
+	walked := []string{}
+	tree.Walk(func(key []string, val interface{}) bool {
+		walked = append(walked, strings.Join(key, " "))
+		return len(walked) < 3
+	})
+	if got := strings.Join(walked, ","); got != "gi,git,git commit" {
+		t.Errorf("Walk: got %s", got)
+	}
+}
+
+func testTokenTreeWithRandomKeys(t *testing.T) {
+	seedRand()
+	tokens := []string{"a", "b", "ab", "世界", ""}
+	randomKey := func() []string {
+		key := []string{}
+		for i := rand.Intn(4) + 1; i > 0; i-- {
+			key = append(key, tokens[rand.Intn(len(tokens))])
+		}
+		return key
+	}
+
+	for i := 0; i < *testRound; i++ {
+		tree := NewTokenTree()
+		dict := map[string][]string{}
+
+		for j := 0; j < *actionCount; j++ {
+			key := randomKey()
+			joined := strings.Join(key, "/")
+			if rand.Intn(3) == 0 {
+				_, exists := dict[joined]
+				if tree.Remove(key) != exists {
+					t.Fatalf("Remove(%v): incorrect result, seed: %d", key, *seed)
+				}
+				delete(dict, joined)
+			} else {
+				tree.Insert(key, joined)
+				dict[joined] = key
+			}
+		}
+		if tree.Size() != len(dict) {
+			t.Fatalf("Size: got %d expect %d, seed: %d", tree.Size(), len(dict), *seed)
+		}
+
+		for joined, key := range dict {
+			if val, err := tree.Get(key); err != nil || val.(string) != joined {
+				t.Fatalf("Get(%v): got %v %v, seed: %d", key, val, err, *seed)
+			}
+		}
+
+		walked := []string{}
+		tree.Walk(func(key []string, val interface{}) bool {
+			walked = append(walked, strings.Join(key, "/"))
+			return true
+		})
+		if len(walked) != len(dict) || !sort.SliceIsSorted(walked, func(i, j int) bool {
+			return strings.Join(dict[walked[i]], "\x00") < strings.Join(dict[walked[j]], "\x00")
+		}) {
+			t.Fatalf("Walk: got %v, seed: %d", walked, *seed)
+		}
+
+		for j := 0; j < *actionCount; j++ {
+			key := randomKey()
+			expected := 0
+			for k := 1; k <= len(key); k++ {
+				if _, ok := dict[strings.Join(key[:k], "/")]; ok {
+					expected++
+				}
+			}
+			if matches := tree.PrefixMatches(key); len(matches) != expected {
+				t.Fatalf("PrefixMatches(%v): got %d expect %d, seed: %d", key, len(matches), expected, *seed)
+			}
+
+			longer := tree.GetLongerMatches(key, len(dict)+1)
+			expected = 0
+			for _, stored := range dict {
+				if len(stored) > len(key) && commonTokens(stored, key) == len(key) {
+					expected++
+				}
+			}
+			if len(longer) != expected {
+				t.Fatalf("GetLongerMatches(%v): got %d expect %d, seed: %d", key, len(longer), expected, *seed)
+			}
+		}
+	}
+}
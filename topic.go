@@ -0,0 +1,144 @@
+package qradix
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrInvalidFilter = errors.New("invalid topic filter")
+)
+
+const (
+	topicSeparator    = "/"
+	singleLevelWild   = "+"
+	multiLevelWild    = "#"
+	systemTopicPrefix = "$"
+)
+
+// TopicTree stores MQTT style subscription filters, levels of filters are separated by "/",
+// "+" matches exactly one level and "#" matches any number of levels at the end of a filter.
+// Like MQTT, wildcards in the first level don't match topics starting with "$".
+// TopicTree is safe for concurrent use.
+type TopicTree struct {
+	tree *RTree
+}
+
+// NewTopicTree returns an empty TopicTree
+func NewTopicTree() *TopicTree {
+	return &TopicTree{tree: NewRTree()}
+}
+
+// validFilter checks wildcards in the filter:
+// "+" and "#" must occupy a whole level and "#" must be the last level
+func validFilter(filter string) bool {
+	if len(filter) == 0 {
+		return false
+	}
+	levels := strings.Split(filter, topicSeparator)
+	for i, level := range levels {
+		if level == multiLevelWild {
+			if i != len(levels)-1 {
+				return false
+			}
+		} else if level != singleLevelWild && strings.ContainsAny(level, singleLevelWild+multiLevelWild) {
+			return false
+		}
+	}
+	return true
+}
+
+// Subscribe stores the value with the filter, and the old value is returned if the filter exists
+func (T *TopicTree) Subscribe(filter string, val interface{}) (interface{}, error) {
+	if !validFilter(filter) {
+		return nil, ErrInvalidFilter
+	}
+	return T.tree.Insert(filter, val)
+}
+
+// Unsubscribe removes the filter, it returns false if the filter doesn't exist
+func (T *TopicTree) Unsubscribe(filter string) bool {
+	return T.tree.Remove(filter)
+}
+
+// Get returns the value of the filter
+func (T *TopicTree) Get(filter string) (interface{}, error) {
+	return T.tree.Get(filter)
+}
+
+// Size returns the number of filters
+func (T *TopicTree) Size() int {
+	return T.tree.Size()
+}
+
+// Match returns all filters and their values matching the topic in lexicographic order of filters,
+// it returns nothing if the topic is empty or contains wildcards.
+// The time is proportional to the depth of the topic and the number of wildcards along the way.
+func (T *TopicTree) Match(topic string) []KV {
+	matches := []KV{}
+	if len(topic) == 0 || strings.ContainsAny(topic, singleLevelWild+multiLevelWild) {
+		return matches
+	}
+
+	T.tree.m.RLock()
+	m := &topicMatcher{
+		levels:  strings.Split(topic, topicSeparator),
+		system:  strings.HasPrefix(topic, systemTopicPrefix),
+		matches: matches,
+	}
+	m.matchLevel(newCursor(T.tree), "", 0)
+	T.tree.m.RUnlock()
+
+	sort.Sort(kvsByKey(m.matches))
+	return m.matches
+}
+
+// topicMatcher walks along filters in the tree with levels of a topic
+type topicMatcher struct {
+	levels  []string
+	system  bool // system is true if the topic starts with "$"
+	matches []KV
+}
+
+// matchLevel matches the i-th level, the cursor c is at the start of a level of filters
+func (m *topicMatcher) matchLevel(c cursor, filter string, i int) {
+	if i == len(m.levels) {
+		m.collect(c, filter)
+		// "a/#" matches "a" as well
+		if c.stepString(topicSeparator + multiLevelWild) {
+			m.collect(c, filter+topicSeparator+multiLevelWild)
+		}
+		return
+	}
+
+	if i > 0 || !m.system {
+		multi := c
+		if multi.stepString(multiLevelWild) {
+			m.collect(multi, filter+multiLevelWild)
+		}
+		single := c
+		if single.stepString(singleLevelWild) {
+			m.nextLevel(single, filter+singleLevelWild, i)
+		}
+	}
+	if c.stepString(m.levels[i]) {
+		m.nextLevel(c, filter+m.levels[i], i)
+	}
+}
+
+// nextLevel moves to the next level after the i-th level is matched
+func (m *topicMatcher) nextLevel(c cursor, filter string, i int) {
+	if i+1 == len(m.levels) {
+		m.matchLevel(c, filter, i+1)
+	} else if c.stepString(topicSeparator) {
+		m.matchLevel(c, filter+topicSeparator, i+1)
+	}
+}
+
+// collect adds the filter if the cursor stops at the end of it
+func (m *topicMatcher) collect(c cursor, filter string) {
+	if val, ok := c.value(); ok {
+		m.matches = append(m.matches, KV{Key: filter, Val: val})
+	}
+}
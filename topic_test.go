@@ -0,0 +1,148 @@
+package qradix
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestTopicTree(t *testing.T) {
+	t.Run("test Subscribe and Match", testTopicTree)
+	t.Run("test Match with random filters", testTopicTreeWithRandomFilters)
+}
+
+func testTopicTree(t *testing.T) {
+	topics := NewTopicTree()
+	filters := []string{
+		"sensors/+/temp",
+		"sensors/#",
+		"sensors/kitchen/temp",
+		"sensors/kitchen",
+		"sensors/+",
+		"+/+/+",
+		"#",
+		"+/kitchen/#",
+		"$SYS/#",
+		"$SYS/+/load",
+		"a//b",
+		"a/+/b",
+	}
+	for _, filter := range filters {
+		if _, err := topics.Subscribe(filter, filter); err != nil {
+			t.Fatalf("Subscribe(%s): %s", filter, err)
+		}
+	}
+	for _, filter := range []string{"", "a/#/b", "a/b#", "a+/b", "#/a"} {
+		if _, err := topics.Subscribe(filter, filter); err != ErrInvalidFilter {
+			t.Errorf("Subscribe(%s): should be invalid", filter)
+		}
+	}
+
+	cases := map[string]string{
+		"sensors/kitchen/temp": "#,+/+/+,+/kitchen/#,sensors/#,sensors/+/temp,sensors/kitchen/temp",
+		"sensors/kitchen":      "#,+/kitchen/#,sensors/#,sensors/+,sensors/kitchen",
+		"sensors":              "#,sensors/#",
+		"sensors/":             "#,sensors/#,sensors/+",
+		"other/kitchen":        "#,+/kitchen/#",
+		"$SYS/broker/load":     "$SYS/#,$SYS/+/load",
+		"$SYS":                 "$SYS/#",
+		"a//b":                 "#,+/+/+,a/+/b,a//b",
+		"sensors/+":            "",
+		"":                     "",
+	}
+	for topic, expected := range cases {
+		matched := []string{}
+		for _, kv := range topics.Match(topic) {
+			if kv.Val.(string) != kv.Key {
+				t.Errorf("Match(%s): invalid value %+v", topic, kv)
+			}
+			matched = append(matched, kv.Key)
+		}
+		if got := strings.Join(matched, ","); got != expected {
+			t.Errorf("Match(%s): got %s expect %s", topic, got, expected)
+		}
+	}
+
+	if !topics.Unsubscribe("#") || topics.Unsubscribe("#") || topics.Size() != len(filters)-1 {
+		t.Fatal("Unsubscribe: failed to unsubscribe")
+	}
+	if got := topics.Match("sensors"); len(got) != 1 || got[0].Key != "sensors/#" {
+		t.Fatalf("Match: got %+v after unsubscribing", got)
+	}
+}
+
+// matchTopic matches the topic with the filter level by level
+func matchTopic(filter, topic string) bool {
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+	for i, level := range filterLevels {
+		if level == "#" {
+			return i > 0 || !strings.HasPrefix(topic, "$")
+		} else if i >= len(topicLevels) {
+			return false
+		} else if level == "+" {
+			if i == 0 && strings.HasPrefix(topic, "$") {
+				return false
+			}
+		} else if level != topicLevels[i] {
+			return false
+		}
+	}
+	return len(filterLevels) == len(topicLevels)
+}
+
+func randomTopic(wildcard bool) string {
+	levelCount := rand.Intn(4) + 1
+	levels := []string{}
+	for i := 0; i < levelCount; i++ {
+		choices := []string{"a", "b", "", "$s"}
+		if wildcard {
+			choices = append(choices, "+", "+")
+			if i == levelCount-1 {
+				choices = append(choices, "#", "#")
+			}
+		}
+		levels = append(levels, choices[rand.Intn(len(choices))])
+	}
+	if topic := strings.Join(levels, "/"); len(topic) > 0 {
+		return topic
+	}
+	return randomTopic(wildcard)
+}
+
+func testTopicTreeWithRandomFilters(t *testing.T) {
+	seedRand()
+	for i := 0; i < *testRound; i++ {
+		topics := NewTopicTree()
+		filters := map[string]bool{}
+		for j := 0; j < *actionCount; j++ {
+			filter := randomTopic(true)
+			if rand.Intn(4) == 0 {
+				topics.Unsubscribe(filter)
+				delete(filters, filter)
+			} else if _, err := topics.Subscribe(filter, j); err == nil {
+				filters[filter] = true
+			}
+		}
+
+		for j := 0; j < *actionCount; j++ {
+			topic := randomTopic(false)
+			expected := 0
+			for filter := range filters {
+				if matchTopic(filter, topic) {
+					expected++
+				}
+			}
+
+			matches := topics.Match(topic)
+			for _, kv := range matches {
+				if !matchTopic(kv.Key, topic) {
+					t.Fatalf("Match(%s): %s should not match, seed: %d", topic, kv.Key, *seed)
+				}
+			}
+			if len(matches) != expected {
+				t.Fatalf("Match(%s): got %d filters expect %d, seed: %d", topic, len(matches), expected, *seed)
+			}
+		}
+	}
+}
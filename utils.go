@@ -43,6 +43,14 @@ func BFS(T *RTree, apply func(Node)) {
 	}
 }
 
+// Root returns the first node of the first level, it returns false if the tree is empty.
+// The tree is only locked while getting the root, so the returned Node is walked without the tree lock.
+func (T *RTree) Root() (Node, bool) {
+	T.m.RLock()
+	defer T.m.RUnlock()
+	return T.root, T.root != nil
+}
+
 func print(msg string) {
 	if *debug {
 		fmt.Println(msg)